@@ -0,0 +1,32 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package Messages
+
+import (
+	"time"
+)
+
+// Snapshot - The latest of everything that has been sent up to Timestamp. Timing and Location are by driver number.
+type Snapshot struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	Event       Event                `json:"event"`
+	Timing      map[int]Timing       `json:"timing"`
+	Weather     Weather              `json:"weather"`
+	Location    map[int]Location     `json:"location"`
+	Drivers     Drivers              `json:"drivers"`
+	RaceControl []RaceControlMessage `json:"race_control"`
+}
@@ -0,0 +1,54 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package Messages
+
+import (
+	"time"
+)
+
+type StreamType int
+
+const (
+	WeatherStream StreamType = iota
+	RaceControlStream
+	TimingStream
+	EventStream
+	TelemetryStream
+	LocationStream
+	EventTimeStream
+	RadioStream
+	DriversStream
+	TimingDeltaStream
+
+	// Only used for backpressure policies and dropped counts, they are the Stream channel and the messages waiting for
+	// subscription handlers. Messages never have these types.
+	EnvelopeStream
+	SubscriptionStream
+)
+
+func (s StreamType) String() string {
+	return [...]string{"Weather", "Race Control", "Timing", "Event", "Telemetry", "Location", "Event Time", "Radio", "Drivers",
+		"Timing Delta", "Stream", "Subscriptions"}[s]
+}
+
+// Envelope - Any message from the session. Data holds the message for the Type, so Weather for WeatherStream,
+// Timing for TimingStream and so on. Sequence goes up by one for each message so a gap means messages were lost.
+type Envelope struct {
+	Sequence  uint64     `json:"sequence"`
+	Type      StreamType `json:"type"`
+	Timestamp time.Time  `json:"timestamp"`
+	Data      any        `json:"data"`
+}
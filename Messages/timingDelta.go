@@ -0,0 +1,95 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package Messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TimingDelta - The fields of a driver's Timing that have changed since the previous delta for them. Changes uses
+// the json names of the fields, for example "position" or "gap_to_leader", and holds the new values as json.
+type TimingDelta struct {
+	Timestamp time.Time `json:"timestamp"`
+	Number    int       `json:"number"`
+
+	Changes map[string]json.RawMessage `json:"changes"`
+}
+
+// The fields that can change, by json name. Timestamp and Number are always in the delta.
+var timingFields = func() map[string]int {
+	result := map[string]int{}
+	timingType := reflect.TypeOf(Timing{})
+	for x := 0; x < timingType.NumField(); x++ {
+		name := strings.Split(timingType.Field(x).Tag.Get("json"), ",")[0]
+		if name != "timestamp" && name != "number" {
+			result[name] = x
+		}
+	}
+	return result
+}()
+
+// CreateTimingDelta - What changed from previous to current. Compare with an empty Timing to get every field.
+func CreateTimingDelta(previous Timing, current Timing) (TimingDelta, error) {
+	result := TimingDelta{
+		Timestamp: current.Timestamp,
+		Number:    current.Number,
+		Changes:   map[string]json.RawMessage{},
+	}
+
+	previousValue := reflect.ValueOf(previous)
+	currentValue := reflect.ValueOf(current)
+	for name, index := range timingFields {
+		value := currentValue.Field(index).Interface()
+		if reflect.DeepEqual(previousValue.Field(index).Interface(), value) {
+			continue
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return TimingDelta{}, err
+		}
+		result.Changes[name] = data
+	}
+
+	return result, nil
+}
+
+// Apply - Update timing with the changes
+func (t TimingDelta) Apply(timing *Timing) error {
+	timingValue := reflect.ValueOf(timing).Elem()
+	for name, data := range t.Changes {
+		index, exists := timingFields[name]
+		if !exists {
+			return fmt.Errorf("unknown timing field '%s'", name)
+		}
+
+		// Decode into a new value so slices are replaced instead of merged
+		value := reflect.New(timingValue.Field(index).Type())
+		err := json.Unmarshal(data, value.Interface())
+		if err != nil {
+			return fmt.Errorf("timing field '%s': %v", name, err)
+		}
+		timingValue.Field(index).Set(value.Elem())
+	}
+
+	timing.Timestamp = t.Timestamp
+	timing.Number = t.Number
+	return nil
+}
@@ -0,0 +1,67 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Serves a recorded session as if it was happening live so applications can be tested without a race happening.
+//
+//	fakelive -dir <session folder> [-speed 10] [-addr localhost:8080]
+//	fakelive -archive <archive file> [-speed 10] [-addr localhost:8080]
+//
+// Connect using f1gopherlib.WithLiveUrl("http://<addr>/signalr").
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/fakeLive"
+)
+
+func main() {
+	dir := flag.String("dir", "", "folder containing the '<Topic>.jsonStream' files for a session")
+	archive := flag.String("archive", "", "archive recorded from a live session")
+	speed := flag.Float64("speed", 1, "how many times faster than realtime to play the session")
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	flag.Parse()
+
+	log := f1log.CreateLog()
+	log.SetLogOutput(os.Stderr)
+
+	var server *fakeLive.Server
+	var err error
+	switch {
+	case len(*dir) > 0:
+		server, err = fakeLive.CreateFromDir(*dir, *speed, log)
+	case len(*archive) > 0:
+		server, err = fakeLive.CreateFromArchive(*archive, *speed, log)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Loading session: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Serving %s of data on http://%s/signalr\n", server.Duration(), *addr)
+
+	err = http.ListenAndServe(*addr, server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
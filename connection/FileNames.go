@@ -39,6 +39,15 @@ const ContentStreamsFile = "ContentStreams"
 const EndOfDataFile = "EndOfData"
 const CatchupFile = "Catchup"
 
+// Sent when a replay moves to a different time, the timestamp is the new time. The data is SeekRestart if the
+// replay has started again from the beginning so anything worked out from the data so far is no longer valid.
+const SeekFile = "Seek"
+const SeekRestart = "restart"
+
+// Sent by replays once all of the data up to the timestamp has been sent so the state can be saved as a keyframe.
+// When a replay restarts from a keyframe the data of the SeekFile is the time of the keyframe.
+const KeyframeFile = "Keyframe"
+
 var OrderedFiles = [...]string{
 	DriverListFile,
 	SessionInfoFile,
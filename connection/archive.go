@@ -0,0 +1,305 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+)
+
+// An archive starts with a line containing the magic value and version followed by a line holding the
+// header as json. The rest of the file is a gzip stream of payloads, one json object per line.
+//
+// Archives without the magic line are the original format of three lines per payload (name, data and
+// timestamp) and are treated as version 1.
+const archiveMagic = "F1GOPHER-ARCHIVE"
+
+const LegacyArchiveVersion = 1
+const ArchiveVersion = 2
+
+const ArchiveExtension = ".f1archive"
+
+// ArchiveHeader - Describes the event that was recorded into an archive
+type ArchiveHeader struct {
+	Version           int                  `json:"version"`
+	Recorded          time.Time            `json:"recorded"`
+	Country           string               `json:"country"`
+	Name              string               `json:"name"`
+	Session           Messages.SessionType `json:"session"`
+	RaceTime          time.Time            `json:"race_time"`
+	EventTime         time.Time            `json:"event_time"`
+	Timezone          string               `json:"timezone"`
+	Track             string               `json:"track"`
+	TrackYear         int                  `json:"track_year"`
+	TimeLostInPitlane time.Duration        `json:"time_lost_in_pitlane"`
+	Url               string               `json:"url"`
+}
+
+type archivedPayload struct {
+	Name      string `json:"name"`
+	Data      []byte `json:"data"`
+	Timestamp string `json:"timestamp"`
+}
+
+type archiveWriter struct {
+	file       *os.File
+	compressed *gzip.Writer
+	encoder    *json.Encoder
+	lock       sync.Mutex
+}
+
+func createArchive(path string, header ArchiveHeader) (*archiveWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Version = ArchiveVersion
+	if header.Recorded.IsZero() {
+		header.Recorded = time.Now().UTC()
+	}
+
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(file, "%s %d\n%s\n", archiveMagic, ArchiveVersion, headerData)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	compressed := gzip.NewWriter(file)
+
+	return &archiveWriter{
+		file:       file,
+		compressed: compressed,
+		encoder:    json.NewEncoder(compressed),
+	}, nil
+}
+
+func (a *archiveWriter) write(data Payload) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	err := a.encoder.Encode(archivedPayload{
+		Name:      data.Name,
+		Data:      data.Data,
+		Timestamp: data.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Flush each payload so if we don't shutdown cleanly the archive is still usable up to that point
+	return a.compressed.Flush()
+}
+
+func (a *archiveWriter) close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	err := a.compressed.Close()
+	return errors.Join(err, a.file.Close())
+}
+
+type archiveReader struct {
+	header ArchiveHeader
+	file   *os.File
+
+	legacy     *bufio.Scanner
+	compressed *gzip.Reader
+	decoder    *json.Decoder
+}
+
+// ReadArchiveHeader - Read the event information stored in an archive. Archives from before the information was
+// recorded return an empty header with the LegacyArchiveVersion.
+func ReadArchiveHeader(path string) (ArchiveHeader, error) {
+	reader, err := openArchive(path)
+	if err != nil {
+		return ArchiveHeader{}, err
+	}
+	defer reader.close()
+
+	return reader.header, nil
+}
+
+// ReadArchive - Read the event information and all the data recorded into an archive
+func ReadArchive(path string) (ArchiveHeader, []Payload, error) {
+	reader, err := openArchive(path)
+	if err != nil {
+		return ArchiveHeader{}, nil, err
+	}
+	defer reader.close()
+
+	var result []Payload
+	for {
+		data, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return reader.header, result, nil
+		}
+		if err != nil {
+			return ArchiveHeader{}, nil, err
+		}
+
+		result = append(result, data)
+	}
+}
+
+func openArchive(path string) (*archiveReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(file)
+
+	start, err := buffered.Peek(len(archiveMagic))
+	if err != nil || string(start) != archiveMagic {
+		// Original format so restart from the beginning
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return &archiveReader{
+			header: ArchiveHeader{Version: LegacyArchiveVersion},
+			file:   file,
+			legacy: newLineScanner(file),
+		}, nil
+	}
+
+	magic, err := buffered.ReadString('\n')
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("archive '%s' is missing the header: %w", path, err)
+	}
+
+	var version int
+	_, err = fmt.Sscanf(strings.TrimSpace(magic), archiveMagic+" %d", &version)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("archive '%s' has an invalid version: %w", path, err)
+	}
+
+	if version > ArchiveVersion {
+		file.Close()
+		return nil, fmt.Errorf("archive '%s' is version %d but only up to version %d is supported", path, version, ArchiveVersion)
+	}
+
+	headerData, err := buffered.ReadBytes('\n')
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("archive '%s' is missing the header: %w", path, err)
+	}
+
+	var header ArchiveHeader
+	err = json.Unmarshal(headerData, &header)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("archive '%s' has an invalid header: %w", path, err)
+	}
+	header.Version = version
+
+	result := &archiveReader{
+		header: header,
+		file:   file,
+	}
+
+	result.compressed, err = gzip.NewReader(buffered)
+	if err != nil {
+		// An archive that was created but never had any data written to it
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+
+		file.Close()
+		return nil, fmt.Errorf("archive '%s' has invalid data: %w", path, err)
+	}
+	result.decoder = json.NewDecoder(result.compressed)
+
+	return result, nil
+}
+
+// Returns the next payload in the archive or io.EOF when there are none left
+func (a *archiveReader) next() (Payload, error) {
+	if a.legacy != nil {
+		return a.nextLegacy()
+	}
+
+	if a.decoder == nil {
+		return Payload{}, io.EOF
+	}
+
+	var data archivedPayload
+	err := a.decoder.Decode(&data)
+	if err != nil {
+		// A recording that wasn't shutdown cleanly won't have the end of the gzip stream
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Payload{}, io.EOF
+		}
+		return Payload{}, err
+	}
+
+	return Payload{
+		Name:      data.Name,
+		Data:      data.Data,
+		Timestamp: data.Timestamp,
+	}, nil
+}
+
+func (a *archiveReader) nextLegacy() (Payload, error) {
+	if !a.legacy.Scan() {
+		return Payload{}, io.EOF
+	}
+	line1 := a.legacy.Text()
+
+	if !a.legacy.Scan() {
+		return Payload{}, errors.New("unexpected EOF, missing second line")
+	}
+	line2 := []byte(a.legacy.Text())
+
+	if !a.legacy.Scan() {
+		return Payload{}, errors.New("unexpected EOF, missing third line")
+	}
+	line3 := a.legacy.Text()
+
+	return Payload{
+		Name:      line1,
+		Data:      line2,
+		Timestamp: line3,
+	}, nil
+}
+
+func (a *archiveReader) close() error {
+	if a.compressed != nil {
+		a.compressed.Close()
+	}
+	return a.file.Close()
+}
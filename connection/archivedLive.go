@@ -16,9 +16,9 @@
 package connection
 
 import (
-	"bufio"
 	"context"
-	"os"
+	"errors"
+	"io"
 	"sync"
 	"time"
 
@@ -28,7 +28,7 @@ import (
 type archivedLive struct {
 	log         *f1log.F1GopherLibLog
 	path        string
-	archiveFile *bufio.Scanner
+	archiveFile *archiveReader
 
 	dataFeed chan Payload
 
@@ -52,12 +52,12 @@ func CreateArchivedLive(
 }
 
 func (a *archivedLive) Connect() (error, <-chan Payload) {
-	f, err := os.Open(a.path)
+	var err error
+	a.archiveFile, err = openArchive(a.path)
 	if err != nil {
 		a.log.Errorf("Archived Live can't open file '%s': %s", a.path, err)
 		return err, nil
 	}
-	a.archiveFile = bufio.NewScanner(f)
 
 	go a.readEntries()
 
@@ -67,35 +67,29 @@ func (a *archivedLive) Connect() (error, <-chan Payload) {
 func (a *archivedLive) readEntries() {
 	a.wg.Add(1)
 	defer a.wg.Done()
+	defer a.archiveFile.close()
 
 	// Will read entries as fast as possible until the channel is full
 	// and then wait. Flow control for message timing is handled elsewhere
-	for a.archiveFile.Scan() {
-
+	for {
 		select {
 		case <-a.ctx.Done():
 			return
 		default:
 		}
 
-		line1 := a.archiveFile.Text()
-
-		if !a.archiveFile.Scan() {
-			a.log.Error("Archived Live unexpected EOF, missing second line")
+		data, err := a.archiveFile.next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				a.log.Errorf("Archived Live reading '%s': %s", a.path, err)
+			}
 			return
 		}
-		line2 := []byte(a.archiveFile.Text())
 
-		if !a.archiveFile.Scan() {
-			a.log.Error("Archived Live unexpected EOF, missing third line")
+		select {
+		case <-a.ctx.Done():
 			return
-		}
-		line3 := a.archiveFile.Text()
-
-		a.dataFeed <- Payload{
-			Name:      line1,
-			Data:      line2,
-			Timestamp: line3,
+		case a.dataFeed <- data:
 		}
 	}
 }
@@ -103,3 +97,14 @@ func (a *archivedLive) readEntries() {
 func (a *archivedLive) IncrementTime(amount time.Duration) {}
 
 func (a *archivedLive) JumpToStart() time.Time { return time.Time{} }
+
+// The data is read as fast as possible and the flow control handles the speed
+func (a *archivedLive) SetSpeed(speed float64) {}
+
+func (a *archivedLive) SeekTo(target time.Time, from time.Time) error { return errSeekLive }
+
+func (a *archivedLive) LapStartTime(lap int) (time.Time, error) { return time.Time{}, errSeekLive }
+
+func (a *archivedLive) TimeWhenRemaining(remaining time.Duration) (time.Time, error) {
+	return time.Time{}, errSeekLive
+}
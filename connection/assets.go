@@ -17,9 +17,13 @@ package connection
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 
 	"github.com/stephenhoran/f1gopherlib/f1log"
@@ -30,16 +34,18 @@ type AssetStore interface {
 }
 
 type assets struct {
-	log   *f1log.F1GopherLibLog
-	url   string
-	cache string
+	log    *f1log.F1GopherLibLog
+	url    string
+	cache  string
+	client *http.Client
 }
 
-func CreateAssetStore(url string, cache string, log *f1log.F1GopherLibLog) AssetStore {
+func CreateAssetStore(url string, cache string, log *f1log.F1GopherLibLog, client *http.Client) AssetStore {
 	return &assets{
-		log:   log,
-		url:   url,
-		cache: cache,
+		log:    log,
+		url:    url,
+		cache:  cache,
+		client: clientOrDefault(client),
 	}
 }
 
@@ -56,40 +62,94 @@ func (a *assets) TeamRadio(file string) ([]byte, error) {
 			f.Close()
 
 			var resp *http.Response
-			resp, err = http.Get(url)
+			resp, err = a.fetch(url)
 			if err != nil {
-				a.log.Errorf("Fetching team radio for '%s': %v", url, err)
 				return nil, err
 			}
 			defer resp.Body.Close()
 
-			scanner := bufio.NewScanner(resp.Body)
-
 			err = os.MkdirAll(filepath.Dir(cachedFile), 0755)
+			if err != nil {
+				a.log.Errorf("Creating team radio cache folder for '%s': %v", cachedFile, err)
+				return nil, err
+			}
 
-			// Write body to file - using url as name
+			// Audio is binary so copy it exactly
 			var newFile *os.File
 			newFile, err = os.Create(cachedFile)
-			defer newFile.Close()
-			for scanner.Scan() {
-				_, err = newFile.Write(scanner.Bytes())
-
-				// need newline for scanner to split
-				newFile.WriteString("\n")
+			if err != nil {
+				a.log.Errorf("Creating team radio cache file '%s': %v", cachedFile, err)
+				return nil, err
+			}
+			_, err = io.Copy(newFile, resp.Body)
+			newFile.Close()
+			if err != nil {
+				os.Remove(cachedFile)
+				a.log.Errorf("Caching team radio '%s': %v", url, err)
+				return nil, err
 			}
 			f, err = os.Open(cachedFile)
 		}
 
+		if err != nil {
+			a.log.Errorf("Opening cached team radio '%s': %v", cachedFile, err)
+			return nil, err
+		}
+		defer f.Close()
+
 		return io.ReadAll(bufio.NewReader(f))
 	}
 
 	var resp *http.Response
-	resp, err := http.Get(url)
+	resp, err := a.fetch(url)
 	if err != nil {
-		a.log.Errorf("Fetching team radio for '%s': %v", url, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	return io.ReadAll(bufio.NewReader(resp.Body))
 }
+
+func (a *assets) fetch(url string) (*http.Response, error) {
+	resp, err := a.client.Get(url)
+	if err != nil {
+		a.log.Errorf("Fetching team radio for '%s': %v", url, err)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		a.log.Errorf("Fetching team radio for '%s': %s", url, resp.Status)
+		return nil, fmt.Errorf("team radio request failed: %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+type fsAssets struct {
+	log   *f1log.F1GopherLibLog
+	files fs.FS
+}
+
+// CreateFsAssetStore - Read the assets from files instead of downloading them. Team radio files are looked for
+// using the path from the data and also under a 'TeamRadio' folder to match the layout of the cache.
+func CreateFsAssetStore(files fs.FS, log *f1log.F1GopherLibLog) AssetStore {
+	return &fsAssets{
+		log:   log,
+		files: files,
+	}
+}
+
+func (a *fsAssets) TeamRadio(file string) ([]byte, error) {
+	data, err := fs.ReadFile(a.files, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = fs.ReadFile(a.files, path.Join("TeamRadio", file))
+	}
+
+	if err != nil {
+		a.log.Errorf("Reading team radio file '%s': %v", file, err)
+		return nil, err
+	}
+
+	return data, nil
+}
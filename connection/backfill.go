@@ -0,0 +1,203 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// Sends what has already happened in a live session, read from the static data, before the live data. The live data
+// received in the meantime is held back until the history has been sent and anything the history already had is
+// dropped.
+type backfill struct {
+	log     *f1log.F1GopherLibLog
+	live    Connection
+	history *replay
+
+	dataFeed chan Payload
+
+	ctx context.Context
+	wg  *sync.WaitGroup
+}
+
+// CreateBackfill - Send everything in history as fast as it can be processed before carrying on with live. The
+// history is the static data for the same event, which is updated throughout the session.
+func CreateBackfill(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	live Connection,
+	history *replay) *backfill {
+
+	history.SetBatch(true)
+
+	return &backfill{
+		ctx:      ctx,
+		wg:       wg,
+		log:      log,
+		live:     live,
+		history:  history,
+		dataFeed: make(chan Payload, 1000),
+	}
+}
+
+func (b *backfill) Connect() (error, <-chan Payload) {
+	err, incoming := b.live.Connect()
+	if err != nil {
+		return err, nil
+	}
+
+	err, history := b.history.Connect()
+	if err != nil {
+		return err, nil
+	}
+
+	b.wg.Add(1)
+	go b.run(incoming, history)
+
+	return nil, b.dataFeed
+}
+
+func (b *backfill) run(incoming <-chan Payload, history <-chan Payload) {
+	defer b.wg.Done()
+
+	// Live data waiting for the history to finish and the time of the last history sent
+	var waiting []Payload
+	var sentUpTo time.Time
+
+	for finished := false; !finished; {
+		select {
+		case <-b.ctx.Done():
+			return
+
+		case data, ok := <-incoming:
+			if !ok {
+				incoming = nil
+				continue
+			}
+			waiting = append(waiting, data)
+
+		case data := <-history:
+			switch data.Name {
+			case EndOfDataFile:
+				finished = true
+
+			case KeyframeFile:
+				// Live sessions don't save keyframes
+
+			default:
+				if !b.send(data) {
+					return
+				}
+
+				timestamp, err := time.Parse(time.RFC3339Nano, data.Timestamp)
+				if err == nil {
+					sentUpTo = timestamp
+				}
+			}
+		}
+	}
+
+	if sentUpTo.IsZero() {
+		b.log.Warn("No history available for the live session")
+	} else {
+		b.log.Infof("Live session history sent up to %s", sentUpTo)
+	}
+
+	for _, data := range waiting {
+		if !b.forward(data, sentUpTo) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+
+		case data, ok := <-incoming:
+			if !ok {
+				return
+			}
+
+			if !b.forward(data, sentUpTo) {
+				return
+			}
+		}
+	}
+}
+
+// Sends live data unless the history has already sent it
+func (b *backfill) forward(data Payload, sentUpTo time.Time) bool {
+	if sentUpTo.IsZero() {
+		return b.send(data)
+	}
+
+	if data.Name == CatchupFile {
+		// Only the first catchup comes before the history, any after a reconnect are already after it
+		if len(data.Timestamp) == 0 {
+			data = b.trimCatchup(data, sentUpTo)
+		}
+		return b.send(data)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, data.Timestamp)
+	if err == nil && !timestamp.After(sentUpTo) {
+		return true
+	}
+
+	return b.send(data)
+}
+
+// The catchup is the current state which can be newer than the history so it is still sent, timestamped with the end
+// of the history to keep it in order
+func (b *backfill) trimCatchup(data Payload, sentUpTo time.Time) Payload {
+	data.Timestamp = sentUpTo.Format("2006-01-02T15:04:05.999Z")
+	return trimRaceControl(data, sentUpTo, b.log)
+}
+
+func (b *backfill) send(data Payload) bool {
+	select {
+	case b.dataFeed <- data:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
+}
+
+// Flow control decides when the data is sent so there is nothing to do here
+func (b *backfill) IncrementTime(amount time.Duration) {}
+
+func (b *backfill) JumpToStart() time.Time { return time.Time{} }
+
+func (b *backfill) SetSpeed(speed float64) {}
+
+// SeekTo - The history is part of the live data so moving is up to the live connection
+func (b *backfill) SeekTo(target time.Time, from time.Time) error {
+	return b.live.SeekTo(target, from)
+}
+
+func (b *backfill) LapStartTime(lap int) (time.Time, error) {
+	return b.live.LapStartTime(lap)
+}
+
+func (b *backfill) TimeWhenRemaining(remaining time.Duration) (time.Time, error) {
+	return b.live.TimeWhenRemaining(remaining)
+}
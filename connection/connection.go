@@ -16,9 +16,17 @@
 package connection
 
 import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
 	"time"
 )
 
+// Some lines, like the catchup or initial state for a topic, are longer than the default scanner allows
+const maxLineLength = 16 * 1024 * 1024
+
 type Payload struct {
 	Name      string
 	Data      []byte
@@ -31,4 +39,38 @@ type Connection interface {
 	IncrementTime(amount time.Duration)
 
 	JumpToStart() time.Time
+
+	SetSpeed(speed float64)
+
+	SeekTo(target time.Time, from time.Time) error
+	LapStartTime(lap int) (time.Time, error)
+	TimeWhenRemaining(remaining time.Duration) (time.Time, error)
+}
+
+var errSeekLive = errors.New("live data can't be moved to a different time")
+
+// The topics to use in the OrderedFiles order, everything when none have been given
+func selectedTopics(topics []string) []string {
+	result := make([]string, 0, len(OrderedFiles))
+	for _, name := range OrderedFiles {
+		if topics == nil || slices.Contains(topics, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Use the default client if one hasn't been provided so we don't need to check everywhere we make a request
+func clientOrDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+
+	return client
+}
+
+func newLineScanner(data io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	return scanner
 }
@@ -0,0 +1,437 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// Keeps everything a live connection receives in a file so the session can be sent again from the nearest keyframe
+// when moving back in time. The timing is left to realtime flow control which holds back anything after the current
+// time.
+type dvr struct {
+	log  *f1log.F1GopherLibLog
+	live Connection
+	dir  string
+
+	// One JSON payload per line so reading can start from any keyframe offset
+	file   *os.File
+	reader *os.File
+
+	// How many payloads and bytes are in the file, the time of the latest, when each lap started and the session clock
+	written int
+	size    int64
+	latest  time.Time
+	laps    map[int]time.Time
+	clock   []sessionClock
+
+	// Where in the file each second of data starts
+	keyframes []dvrKeyframe
+
+	// Where the data is being moved to and the nearest keyframe before it, sent the next time the data is read from
+	// the file
+	seekTarget  time.Time
+	seekFrom    time.Time
+	seekPending bool
+
+	lock     sync.Mutex
+	received chan struct{}
+
+	dataFeed chan Payload
+
+	ctx context.Context
+	wg  *sync.WaitGroup
+}
+
+// Everything in the file before offset is from timestamp or earlier
+type dvrKeyframe struct {
+	timestamp time.Time
+	offset    int64
+	count     int
+}
+
+// CreateDvr - Record everything live receives into a temporary file in dir so the session can be moved back to any
+// time already received using SeekTo. An empty dir uses the default temporary folder. The file is removed on
+// shutdown.
+func CreateDvr(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog, live Connection, dir string) *dvr {
+	return &dvr{
+		ctx:      ctx,
+		wg:       wg,
+		log:      log,
+		live:     live,
+		dir:      dir,
+		laps:     make(map[int]time.Time),
+		received: make(chan struct{}, 1),
+		dataFeed: make(chan Payload, 1000),
+	}
+}
+
+func (d *dvr) Connect() (error, <-chan Payload) {
+	file, err := os.CreateTemp(d.dir, "f1gopherlib-*.dvr")
+	if err != nil {
+		return fmt.Errorf("creating live recording buffer: %v", err), nil
+	}
+
+	reader, err := os.Open(file.Name())
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("opening live recording buffer: %v", err), nil
+	}
+
+	d.file = file
+	d.reader = reader
+
+	err, incoming := d.live.Connect()
+	if err != nil {
+		d.close()
+		return err, nil
+	}
+
+	d.wg.Add(1)
+	go d.run(incoming)
+
+	return nil, d.dataFeed
+}
+
+func (d *dvr) run(incoming <-chan Payload) {
+	defer d.wg.Done()
+	defer d.close()
+
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		d.record(incoming)
+	}()
+	go func() {
+		defer workers.Done()
+		d.forward()
+	}()
+	workers.Wait()
+}
+
+func (d *dvr) close() {
+	d.reader.Close()
+	d.file.Close()
+	os.Remove(d.file.Name())
+}
+
+// Saves everything received from live to the file
+func (d *dvr) record(incoming <-chan Payload) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+
+		case data, ok := <-incoming:
+			if !ok {
+				return
+			}
+
+			encoded, err := json.Marshal(data)
+			if err == nil {
+				_, err = d.file.Write(append(encoded, '\n'))
+			}
+			if err != nil {
+				// Without the file nothing else can be sent
+				d.log.Errorf("Writing to live recording buffer: %v", err)
+				return
+			}
+
+			d.lock.Lock()
+			d.track(data, int64(len(encoded)+1))
+			d.lock.Unlock()
+
+			select {
+			case d.received <- struct{}{}:
+			default:
+				// Already waiting to be read
+			}
+		}
+	}
+}
+
+// Keeps track of what has been received so the latest time, start of each lap, session clock and where each second
+// starts in the file are known. Called with the lock held.
+func (d *dvr) track(data Payload, size int64) {
+	offset := d.size
+	count := d.written
+	d.size += size
+	d.written++
+
+	if data.Name == CatchupFile {
+		var catchup map[string]json.RawMessage
+		if json.Unmarshal(data.Data, &catchup) == nil {
+			if clock, exists := catchup[ExtrapolatedClockFile]; exists {
+				// The catchup has no timestamp so the clock is only usable if it says when it was set
+				d.trackClock(clock, time.Time{})
+			}
+		}
+	}
+
+	if len(data.Timestamp) == 0 {
+		return
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, data.Timestamp)
+	if err != nil {
+		return
+	}
+
+	if !d.latest.IsZero() && timestamp.Truncate(time.Second).After(d.latest.Truncate(time.Second)) {
+		d.keyframes = append(d.keyframes, dvrKeyframe{
+			timestamp: d.latest.Truncate(time.Millisecond),
+			offset:    offset,
+			count:     count,
+		})
+	}
+
+	if timestamp.After(d.latest) {
+		d.latest = timestamp
+	}
+
+	switch data.Name {
+	case LapCountFile:
+		var lapCount struct {
+			CurrentLap *int
+		}
+		if json.Unmarshal(data.Data, &lapCount) == nil && lapCount.CurrentLap != nil {
+			if _, exists := d.laps[*lapCount.CurrentLap]; !exists {
+				d.laps[*lapCount.CurrentLap] = timestamp
+			}
+		}
+
+	case ExtrapolatedClockFile:
+		d.trackClock(data.Data, timestamp)
+	}
+}
+
+// Live clock updates only have what changed so they carry on from the previous one. Called with the lock held.
+func (d *dvr) trackClock(data []byte, timestamp time.Time) {
+	var previous sessionClock
+	if len(d.clock) > 0 {
+		previous = d.clock[len(d.clock)-1]
+	}
+
+	current, valid := readSessionClock(data, timestamp, previous)
+	if valid {
+		d.clock = append(d.clock, current)
+	}
+}
+
+// The keyframe at exactly timestamp
+func (d *dvr) keyframeAt(timestamp time.Time) (dvrKeyframe, int, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	index := sort.Search(len(d.keyframes), func(i int) bool { return !d.keyframes[i].timestamp.Before(timestamp) })
+	if index == len(d.keyframes) || !d.keyframes[index].timestamp.Equal(timestamp) {
+		return dvrKeyframe{}, 0, false
+	}
+
+	return d.keyframes[index], index, true
+}
+
+// The keyframe that comes before the next payload sent, if there is one
+func (d *dvr) nextKeyframe(index int, sent int) (dvrKeyframe, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if index < len(d.keyframes) && d.keyframes[index].count == sent {
+		return d.keyframes[index], true
+	}
+	return dvrKeyframe{}, false
+}
+
+// Sends everything in the file in order, waiting for more when it has all been sent. A keyframe is sent at the start
+// of each second so the parser can save its state there. Moving back in time starts again from the keyframe at the
+// seek from time, or the beginning without one, and moving forward past the data sent jumps to the keyframe too.
+func (d *dvr) forward() {
+	reader := bufio.NewReader(d.reader)
+	sent := 0
+	keyframe := 0
+	var sentUpTo time.Time
+
+	for {
+		d.lock.Lock()
+		available := d.written
+		seekPending := d.seekPending
+		seekTarget := d.seekTarget
+		seekFrom := d.seekFrom
+		d.seekPending = false
+		d.lock.Unlock()
+
+		if seekPending {
+			seek := Payload{Name: SeekFile, Timestamp: seekTarget.Format("2006-01-02T15:04:05.999Z")}
+
+			// Where to start reading from, carrying on from where we are if nothing has changed
+			var start *dvrKeyframe
+			startIndex := 0
+			restart := seekTarget.Before(sentUpTo)
+			if restart {
+				start = &dvrKeyframe{}
+				seek.Data = []byte(SeekRestart)
+			}
+			if !seekFrom.IsZero() && (restart || seekFrom.After(sentUpTo)) {
+				if found, index, exists := d.keyframeAt(seekFrom); exists {
+					start = &found
+					startIndex = index + 1
+					seek.Data = []byte(seekFrom.Format("2006-01-02T15:04:05.999Z"))
+				}
+			}
+
+			if start != nil {
+				_, err := d.reader.Seek(start.offset, io.SeekStart)
+				if err != nil {
+					d.log.Errorf("Moving in live recording buffer: %v", err)
+					return
+				}
+				reader.Reset(d.reader)
+				sent = start.count
+				keyframe = startIndex
+				sentUpTo = start.timestamp
+			}
+
+			if !d.send(seek) {
+				return
+			}
+			continue
+		}
+
+		if sent < available {
+			if found, exists := d.nextKeyframe(keyframe, sent); exists {
+				keyframe++
+				if !d.send(Payload{Name: KeyframeFile, Timestamp: found.timestamp.Format("2006-01-02T15:04:05.999Z")}) {
+					return
+				}
+			}
+
+			line, err := reader.ReadBytes('\n')
+			var data Payload
+			if err == nil {
+				err = json.Unmarshal(line, &data)
+			}
+			if err != nil {
+				d.log.Errorf("Reading from live recording buffer: %v", err)
+				return
+			}
+			sent++
+
+			timestamp, err := time.Parse(time.RFC3339Nano, data.Timestamp)
+			if err == nil && timestamp.After(sentUpTo) {
+				sentUpTo = timestamp
+			}
+
+			if !d.send(data) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-d.received:
+		}
+	}
+}
+
+func (d *dvr) send(data Payload) bool {
+	select {
+	case d.dataFeed <- data:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+// Latest - The time of the newest data received from live
+func (d *dvr) Latest() time.Time {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.latest
+}
+
+// Realtime flow control decides when the data is sent so there is nothing to do here
+func (d *dvr) IncrementTime(amount time.Duration) {}
+
+func (d *dvr) JumpToStart() time.Time { return time.Time{} }
+
+func (d *dvr) SetSpeed(speed float64) {}
+
+// SeekTo - Send everything again from the keyframe at from, or the beginning if there isn't one, so the state is
+// rebuilt up to target. Moving forward carries on from the data already sent unless from is after it.
+func (d *dvr) SeekTo(target time.Time, from time.Time) error {
+	d.lock.Lock()
+	if d.latest.IsZero() {
+		d.lock.Unlock()
+		return errors.New("nothing has been received from live yet")
+	}
+	if target.After(d.latest) {
+		target = d.latest
+	}
+	d.seekTarget = target
+	d.seekFrom = from
+	d.seekPending = true
+	d.lock.Unlock()
+
+	select {
+	case d.received <- struct{}{}:
+	default:
+		// Already waiting to be read
+	}
+	return nil
+}
+
+// LapStartTime - When the given lap started, only laps that have already been received can be found
+func (d *dvr) LapStartTime(lap int) (time.Time, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var result time.Time
+	for current, timestamp := range d.laps {
+		if current >= lap && (result.IsZero() || timestamp.Before(result)) {
+			result = timestamp
+		}
+	}
+
+	if result.IsZero() {
+		return time.Time{}, fmt.Errorf("lap %d not found", lap)
+	}
+	return result, nil
+}
+
+// TimeWhenRemaining - When the session clock showed the given time remaining, only times that have already been
+// received can be found
+func (d *dvr) TimeWhenRemaining(remaining time.Duration) (time.Time, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return timeWhenRemaining(d.clock, remaining)
+}
@@ -17,148 +17,351 @@ package connection
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"os"
+	"net/http"
 	"sync"
 	"time"
 
-	"github.com/f1gopher/signalr/v2"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stephenhoran/f1gopherlib/Messages"
 	"github.com/stephenhoran/f1gopherlib/f1log"
 	"golang.org/x/sync/errgroup"
 )
 
+// How long to keep trying to reconnect before giving up on the live session
+const reconnectInitialInterval = time.Second
+const reconnectMaxInterval = 30 * time.Second
+const reconnectMaxElapsedTime = 10 * time.Minute
+
+// Talks to the live timing server. Each message received is passed to receive and the returned group finishes
+// when the connection drops.
+type liveProtocol interface {
+	subscribe(ctx context.Context, topics []string, receive func(ctx context.Context, data Payload)) (*errgroup.Group, error)
+}
+
 type live struct {
-	log     *f1log.F1GopherLibLog
-	archive *os.File
-	ctx     context.Context
-	wg      *sync.WaitGroup
-	c2      *signalr.Conn
-	client  *signalr.Client
+	log      *f1log.F1GopherLibLog
+	ctx      context.Context
+	wg       *sync.WaitGroup
+	protocol liveProtocol
+
+	// Everywhere the live data is saved to as it is received
+	recorders []liveRecorder
+
+	// What to subscribe to, nil for everything
+	topics []string
+
+	// Where changes to the connection state are reported and the last time data was received
+	status        chan<- Messages.ConnectionStatus
+	lastTimestamp string
 
 	dataFeed chan Payload
 }
 
 func CreateLive(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog) *live {
+	return CreateLiveFrom(ctx, wg, log, LiveUrl, nil)
+}
+
+// CreateLiveFrom - Create a live connection to the classic SignalR endpoint at url. If client is nil the
+// http.DefaultClient settings are used.
+func CreateLiveFrom(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	url string,
+	client *http.Client) *live {
+
+	return createLive(ctx, wg, log, &signalrClassic{url: url, client: clientOrDefault(client), log: log})
+}
+
+// CreateLiveCore - Create a live connection that uses the SignalR Core hub at url instead of the classic SignalR
+// endpoint. If client is nil the http.DefaultClient is used for the negotiate request.
+func CreateLiveCore(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	url string,
+	client *http.Client) *live {
+
+	return createLive(ctx, wg, log, &signalrCore{url: url, client: clientOrDefault(client), log: log})
+}
+
+func createLive(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog, protocol liveProtocol) *live {
 	return &live{
 		ctx:      ctx,
 		wg:       wg,
 		log:      log,
+		protocol: protocol,
 		dataFeed: make(chan Payload, 1000),
-		archive:  nil,
 	}
 }
 
-func CreateArchivingLive(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog, archiveFile string) (*live, error) {
-	archive, err := os.Create(fmt.Sprintf("%s_%d.txt", archiveFile, time.Now().UnixMilli()))
+// CreateArchivingLive - Create a live connection that also records everything it receives into an archive file that
+// can be replayed later. The header describes the event being recorded.
+func CreateArchivingLive(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	archiveFile string,
+	header ArchiveHeader) (*live, error) {
+
+	result := CreateLive(ctx, wg, log)
+	err := result.ArchiveTo(archiveFile, header)
 	if err != nil {
 		return nil, err
 	}
 
-	return &live{
-		ctx:      ctx,
-		wg:       wg,
-		log:      log,
-		dataFeed: make(chan Payload, 1000),
-		archive:  archive,
-	}, nil
+	return result, nil
+}
+
+// CreateRecordingLive - Create a live connection that also records everything it receives into a directory of
+// '<Topic>.jsonStream' files with the same layout as the static replay data. Team radio audio is saved using the
+// asset store.
+func CreateRecordingLive(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	dir string,
+	assets AssetStore) (*live, error) {
+
+	result := CreateLive(ctx, wg, log)
+	err := result.RecordTo(dir, assets)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ArchiveTo - Also save everything received into an archive file. Must be called before Connect.
+func (l *live) ArchiveTo(archiveFile string, header ArchiveHeader) error {
+	archive, err := createArchive(fmt.Sprintf("%s_%d%s", archiveFile, time.Now().UnixMilli(), ArchiveExtension), header)
+	if err != nil {
+		return err
+	}
+
+	l.recorders = append(l.recorders, archive)
+	return nil
+}
+
+// RecordTo - Also save everything received into the static replay layout in dir. Must be called before Connect.
+func (l *live) RecordTo(dir string, assets AssetStore) error {
+	recorder, err := createSessionRecorder(dir, assets, l.log)
+	if err != nil {
+		return err
+	}
+
+	l.recorders = append(l.recorders, recorder)
+	return nil
+}
+
+func (l *live) record(data Payload) {
+	for _, recorder := range l.recorders {
+		if err := recorder.write(data); err != nil {
+			l.log.Errorf("Recording live data for '%s': %v", data.Name, err)
+		}
+	}
 }
 
 func (l *live) Connect() (error, <-chan Payload) {
-	var err error
-
-	// Prepare a SignalR client.
-	l.c2, err = signalr.Dial(
-		l.ctx,
-		"https://livetiming.formula1.com/signalr",
-		`[{"name":"streaming"}]`,
-	)
+	l.setStatus(Messages.Connecting, nil)
+
+	session, err := l.protocol.subscribe(l.ctx, selectedTopics(l.topics), l.receive)
 	if err != nil {
 		l.log.Errorf("Connect to live failed: %v", err)
+		l.setStatus(Messages.ConnectionFailed, err)
 		return err, nil
 	}
 
-	l.client = signalr.NewClient("streaming", l.c2)
+	l.log.Info("Connected to live")
+	l.setStatus(Messages.Connected, nil)
 
-	errg, ctx := errgroup.WithContext(l.ctx)
-	errg.Go(func() error { return l.client.Run(ctx) })
-	errg.Go(func() error {
-		l.wg.Add(1)
-		defer l.wg.Done()
+	l.wg.Add(1)
+	go l.maintain(session)
+
+	return nil, l.dataFeed
+}
+
+// SetTopics - Only subscribe to topics instead of everything in OrderedFiles. Must be called before Connect.
+func (l *live) SetTopics(topics []string) {
+	l.topics = topics
+}
+
+// SetStatusOutput - Report changes to the state of the connection on the given channel. Must be called before
+// Connect.
+func (l *live) SetStatusOutput(status chan<- Messages.ConnectionStatus) {
+	l.status = status
+}
+
+func (l *live) setStatus(state Messages.ConnectionState, err error) {
+	if l.status == nil {
+		return
+	}
 
-		stream, err1 := l.client.Callback(ctx, "feed")
-		if err1 != nil {
-			return err1
+	status := Messages.ConnectionStatus{
+		Timestamp: time.Now(),
+		State:     state,
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	select {
+	case l.status <- status:
+	default:
+		l.log.Warnf("Connection status channel is full, dropping state '%s'", state)
+	}
+}
+
+// Keeps the connection going until shutdown, reconnecting and subscribing again whenever the connection drops
+func (l *live) maintain(session *errgroup.Group) {
+	defer l.wg.Done()
+
+	defer func() {
+		for _, recorder := range l.recorders {
+			if err := recorder.close(); err != nil {
+				l.log.Errorf("Closing live recording: %v", err)
+			}
 		}
-		defer stream.Close()
+	}()
 
-		l.log.Info("Waiting for live data...")
+	for {
+		err := session.Wait()
 
-		for {
-			select {
-			case <-ctx.Done():
-				l.log.Info("Live shutdown")
-				return nil
+		if l.ctx.Err() != nil {
+			l.log.Info("Live shutdown")
+			return
+		}
+
+		l.log.Warnf("Live connection lost: %v", err)
+		l.setStatus(Messages.Reconnecting, err)
+
+		retry := backoff.NewExponentialBackOff()
+		retry.InitialInterval = reconnectInitialInterval
+		retry.MaxInterval = reconnectMaxInterval
+		retry.MaxElapsedTime = reconnectMaxElapsedTime
 
-			default:
-				// do nothing
+		err = backoff.RetryNotify(
+			func() error {
+				var subscribeErr error
+				session, subscribeErr = l.protocol.subscribe(l.ctx, selectedTopics(l.topics), l.receive)
+				return subscribeErr
+			},
+			backoff.WithContext(retry, l.ctx),
+			func(err error, wait time.Duration) {
+				l.log.Warnf("Live reconnect failed, retrying in %s: %v", wait, err)
+			})
+
+		if l.ctx.Err() != nil {
+			l.log.Info("Live shutdown")
+			return
+		}
+
+		if err != nil {
+			l.log.Errorf("Live reconnect failed, giving up: %v", err)
+			l.setStatus(Messages.ConnectionFailed, err)
+
+			select {
+			case l.dataFeed <- Payload{Name: EndOfDataFile}:
+			case <-l.ctx.Done():
 			}
+			return
+		}
 
-			res := stream.ReadRaw()
-
-			if res.Args != nil {
-				if len(res.Args) == 3 {
-					data := Payload{}
-					abc, _ := res.Args[0].MarshalJSON()
-					data.Name = string(abc[1 : len(abc)-1])
-					abc, _ = res.Args[1].MarshalJSON()
-					if abc[0] == '"' {
-						data.Data = abc[1 : len(abc)-1]
-					} else {
-						data.Data = abc
-					}
-					abc, _ = res.Args[2].MarshalJSON()
-					data.Timestamp = string(abc[1 : len(abc)-1])
-
-					if l.archive != nil {
-						l.archive.WriteString(data.Name + "\r\n")
-						l.archive.Write(data.Data)
-						l.archive.WriteString("\r\n" + data.Timestamp + "\r\n")
-					}
-
-					l.dataFeed <- data
-				} else if len(res.Args) == 1 {
-					data := Payload{
-						Name:      CatchupFile,
-						Timestamp: "",
-					}
-					abc, _ := res.Args[0].MarshalJSON()
-					data.Data = abc
-
-					if l.archive != nil {
-						l.archive.WriteString(data.Name + "\r\n")
-						l.archive.Write(data.Data)
-						l.archive.WriteString("\r\n" + data.Timestamp + "\r\n")
-					}
-
-					l.dataFeed <- data
-				} else {
-					l.log.Errorf("There is an unhandled number of arguments for live data: %d, dropping data", len(res.Args))
-				}
+		l.log.Info("Reconnected to live")
+		l.setStatus(Messages.Connected, nil)
+	}
+}
+
+func (l *live) receive(ctx context.Context, data Payload) {
+	if data.Name == CatchupFile {
+		// The current state of everything, returned when subscribing. After a reconnect it replaces the state built
+		// up so far so it is timestamped with the last data we had to keep everything in order.
+		if len(l.lastTimestamp) > 0 {
+			lastTime, err := time.Parse(time.RFC3339Nano, l.lastTimestamp)
+			if err == nil {
+				data = trimRaceControl(data, lastTime, l.log)
 			}
 		}
-	})
-	err = l.client.Invoke(ctx, "Subscribe", OrderedFiles).Exec()
-	if err != nil {
-		l.log.Errorf("Live connection subscribe failed: %v", err)
-		return err, nil
+		data.Timestamp = l.lastTimestamp
+	} else {
+		l.lastTimestamp = data.Timestamp
 	}
 
-	l.log.Info("Connected to live")
+	l.record(data)
 
-	return nil, l.dataFeed
+	select {
+	case l.dataFeed <- data:
+	case <-ctx.Done():
+	}
 }
 
 // Can't do anything because this is live data
 func (l *live) IncrementTime(amount time.Duration) {}
 
 func (l *live) JumpToStart() time.Time { return time.Time{} }
+
+func (l *live) SetSpeed(speed float64) {}
+
+func (l *live) SeekTo(target time.Time, from time.Time) error { return errSeekLive }
+
+func (l *live) LapStartTime(lap int) (time.Time, error) { return time.Time{}, errSeekLive }
+
+func (l *live) TimeWhenRemaining(remaining time.Duration) (time.Time, error) {
+	return time.Time{}, errSeekLive
+}
+
+// Race control messages in a catchup are a list of everything so far so only the ones after sentUpTo are kept,
+// the rest have already been sent
+func trimRaceControl(data Payload, sentUpTo time.Time, log *f1log.F1GopherLibLog) Payload {
+	var topics map[string]json.RawMessage
+	err := json.Unmarshal(data.Data, &topics)
+	if err != nil {
+		log.Errorf("Reading live catchup: %v", err)
+		return data
+	}
+
+	raceControl, exists := topics[RaceControlMessagesFile]
+	if !exists {
+		return data
+	}
+
+	var messages struct {
+		Messages []map[string]any
+	}
+	err = json.Unmarshal(raceControl, &messages)
+	if err != nil {
+		// Better to miss some than send them all again
+		log.Warnf("Dropping race control messages from the live catchup: %v", err)
+		delete(topics, RaceControlMessagesFile)
+	} else {
+		newer := make([]map[string]any, 0)
+		for _, msg := range messages.Messages {
+			utc, _ := msg["Utc"].(string)
+			timestamp, err := parseUtc(utc)
+			if err == nil && timestamp.After(sentUpTo) {
+				newer = append(newer, msg)
+			}
+		}
+		messages.Messages = newer
+		topics[RaceControlMessagesFile], _ = json.Marshal(messages)
+	}
+
+	trimmed, err := json.Marshal(topics)
+	if err != nil {
+		log.Errorf("Writing live catchup: %v", err)
+		return data
+	}
+	data.Data = trimmed
+	return data
+}
+
+// Race control messages are timestamped with and without the time zone
+func parseUtc(utc string) (time.Time, error) {
+	timestamp, err := time.Parse(time.RFC3339Nano, utc)
+	if err != nil {
+		return time.Parse("2006-01-02T15:04:05", utc)
+	}
+	return timestamp, nil
+}
@@ -0,0 +1,291 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// Somewhere to save the live data as it is received
+type liveRecorder interface {
+	write(data Payload) error
+	close() error
+}
+
+// Writes live data into the same layout as the static replay files, one '<Topic>.jsonStream' file per topic with
+// each line prefixed by the offset from the start of the data. The team radio audio is saved using the asset
+// store so it ends up in the same place as when it is cached for a replay.
+type sessionRecorder struct {
+	log    *f1log.F1GopherLibLog
+	dir    string
+	assets AssetStore
+
+	files map[string]*os.File
+
+	dataStart      time.Time
+	lastTimestamp  time.Time
+	pendingCatchup []byte
+
+	downloads sync.WaitGroup
+	lock      sync.Mutex
+}
+
+func createSessionRecorder(dir string, assets AssetStore, log *f1log.F1GopherLibLog) (*sessionRecorder, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sessionRecorder{
+		log:    log,
+		dir:    dir,
+		assets: assets,
+		files:  make(map[string]*os.File),
+	}, nil
+}
+
+func (s *sessionRecorder) write(data Payload) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if data.Name == CatchupFile {
+		// Until we have had a message with a timestamp we don't know when the data starts so hold on to the
+		// current state until then
+		if s.dataStart.IsZero() {
+			s.pendingCatchup = data.Data
+			return nil
+		}
+
+		return s.writeCatchup(data.Data, s.lastTimestamp)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, data.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp '%s' for '%s': %w", data.Timestamp, data.Name, err)
+	}
+
+	if s.dataStart.IsZero() {
+		s.dataStart = timestamp
+		s.lastTimestamp = timestamp
+
+		if s.pendingCatchup != nil {
+			err = s.writeCatchup(s.pendingCatchup, timestamp)
+			s.pendingCatchup = nil
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if timestamp.After(s.lastTimestamp) {
+		s.lastTimestamp = timestamp
+	}
+
+	if data.Name == TeamRadioFile {
+		s.fetchTeamRadio(data.Data)
+	}
+
+	return s.writeLine(data.Name, timestamp, data.Data)
+}
+
+func (s *sessionRecorder) writeCatchup(data []byte, timestamp time.Time) error {
+	var topics map[string]json.RawMessage
+	err := json.Unmarshal(data, &topics)
+	if err != nil {
+		return fmt.Errorf("invalid catchup data: %w", err)
+	}
+
+	var result error
+	for _, name := range OrderedFiles {
+		value, exists := topics[name]
+		if !exists || bytes.Equal(value, []byte("null")) {
+			continue
+		}
+
+		if strings.HasSuffix(name, ".z") {
+			var compressed string
+			err = json.Unmarshal(value, &compressed)
+			if err != nil {
+				result = errors.Join(result, fmt.Errorf("invalid catchup data for '%s': %w", name, err))
+				continue
+			}
+			value = []byte(compressed)
+		}
+
+		if name == ExtrapolatedClockFile {
+			value = s.clockAt(value, timestamp)
+		}
+
+		if name == TeamRadioFile {
+			s.fetchTeamRadio(value)
+		}
+
+		result = errors.Join(result, s.writeLine(name, timestamp, value))
+	}
+
+	return result
+}
+
+// The replay works out when the data started from the first clock entry so the catchup value has to be for the
+// time it is written at rather than when the clock was last set
+func (s *sessionRecorder) clockAt(data []byte, timestamp time.Time) []byte {
+	var clock map[string]interface{}
+	if json.Unmarshal(data, &clock) != nil {
+		return data
+	}
+
+	utcStr, _ := clock["Utc"].(string)
+	utc, err := time.Parse(time.RFC3339Nano, utcStr)
+	if err != nil || !utc.Before(timestamp) {
+		return data
+	}
+
+	extrapolating, _ := clock["Extrapolating"].(bool)
+	remainingStr, _ := clock["Remaining"].(string)
+	if extrapolating && len(remainingStr) > 0 {
+		var hours, mins, secs int
+		_, err = fmt.Sscanf(remainingStr, "%d:%d:%d", &hours, &mins, &secs)
+		if err == nil {
+			remaining := time.Duration(hours)*time.Hour + time.Duration(mins)*time.Minute + time.Duration(secs)*time.Second
+			remaining -= timestamp.Sub(utc).Truncate(time.Second)
+			if remaining < 0 {
+				remaining = 0
+			}
+
+			clock["Remaining"] = fmt.Sprintf(
+				"%02d:%02d:%02d",
+				int(remaining.Hours()),
+				int(remaining.Minutes())%60,
+				int(remaining.Seconds())%60)
+		}
+	}
+
+	clock["Utc"] = timestamp.UTC().Format("2006-01-02T15:04:05.000Z")
+
+	result, err := json.Marshal(clock)
+	if err != nil {
+		return data
+	}
+	return result
+}
+
+func (s *sessionRecorder) writeLine(name string, timestamp time.Time, data []byte) error {
+	file, exists := s.files[name]
+	if !exists {
+		var err error
+		file, err = os.Create(filepath.Join(s.dir, name+".jsonStream"))
+		if err != nil {
+			return err
+		}
+		s.files[name] = file
+	}
+
+	offset := timestamp.Sub(s.dataStart)
+	if offset < 0 {
+		offset = 0
+	}
+
+	var line bytes.Buffer
+	line.WriteString(fmt.Sprintf(
+		"%02d:%02d:%02d.%03d",
+		int(offset.Hours()),
+		int(offset.Minutes())%60,
+		int(offset.Seconds())%60,
+		offset.Milliseconds()%1000))
+
+	if strings.HasSuffix(name, ".z") {
+		line.WriteString("\"")
+		line.Write(data)
+		line.WriteString("\"")
+	} else if err := json.Compact(&line, data); err != nil {
+		return fmt.Errorf("invalid data for '%s': %w", name, err)
+	}
+	line.WriteString("\n")
+
+	_, err := file.Write(line.Bytes())
+	return err
+}
+
+// Download the audio for any team radio messages in the background so it is available for replays
+func (s *sessionRecorder) fetchTeamRadio(data []byte) {
+	if s.assets == nil {
+		return
+	}
+
+	var radio map[string]interface{}
+	if json.Unmarshal(data, &radio) != nil {
+		return
+	}
+
+	var captures []interface{}
+	switch value := radio["Captures"].(type) {
+	case []interface{}:
+		captures = value
+	case map[string]interface{}:
+		for _, capture := range value {
+			captures = append(captures, capture)
+		}
+	default:
+		return
+	}
+
+	for _, capture := range captures {
+		record, ok := capture.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path, _ := record["Path"].(string)
+		if len(path) == 0 {
+			continue
+		}
+
+		s.downloads.Add(1)
+		go func() {
+			defer s.downloads.Done()
+
+			_, err := s.assets.TeamRadio(path)
+			if err != nil {
+				s.log.Errorf("Recording team radio '%s': %v", path, err)
+			}
+		}()
+	}
+}
+
+func (s *sessionRecorder) close() error {
+	s.downloads.Wait()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var result error
+	for _, file := range s.files {
+		result = errors.Join(result, file.Close())
+	}
+	s.files = make(map[string]*os.File)
+
+	return result
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -36,28 +37,80 @@ import (
 type fileInfo struct {
 	name         string
 	data         *bufio.Scanner
+	closer       io.Closer
 	nextLine     string
 	nextLineTime time.Time
 }
 
+func (f *fileInfo) close() {
+	if f.closer != nil {
+		f.closer.Close()
+	}
+	f.data = nil
+	f.closer = nil
+}
+
+// When each lap started
+type lapStart struct {
+	lap       int
+	timestamp time.Time
+}
+
+// What the session clock showed
+type sessionClock struct {
+	utc           time.Time
+	remaining     time.Duration
+	extrapolating bool
+}
+
 type replay struct {
 	log      *f1log.F1GopherLibLog
 	cache    string
+	client   *http.Client
 	dataFeed chan Payload
 
+	// When set the data files are read from here instead of being downloaded from the event url
+	files fs.FS
+
 	eventUrl  string
 	session   Messages.SessionType
 	eventYear int
 
 	dataFiles []fileInfo
 
+	// Send everything as fast as it can be processed instead of in realtime
+	batch bool
+
+	// Which files to read, nil for everything
+	topics []string
+
 	ctx context.Context
 	wg  *sync.WaitGroup
 
 	currentTime     time.Time
+	seekTarget      time.Time
+	seekFrom        time.Time
+	finished        bool
 	currentTimeLock sync.Mutex
 
+	// Wakes up the reader to move to seekTarget
+	seekRequested chan struct{}
+
+	// How many times faster than realtime to send the data
+	speed     float64
+	speedLock sync.Mutex
+
+	// Guarded by currentTimeLock
 	raceStartTime time.Time
+
+	// Read from the files the first time they are needed so they are only downloaded once
+	dataStartTime    time.Time
+	sessionStartTime time.Time
+	laps             []lapStart
+	lapsRead         bool
+	clock            []sessionClock
+	clockRead        bool
+	indexLock        sync.Mutex
 }
 
 const NotFoundResponse = "<?xml version='1.0' encoding='UTF-8'?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>"
@@ -69,17 +122,43 @@ func CreateReplay(
 	url string,
 	session Messages.SessionType,
 	eventYear int,
-	cache string) *replay {
+	cache string,
+	client *http.Client) *replay {
+
+	return &replay{
+		ctx:           ctx,
+		wg:            wg,
+		log:           log,
+		dataFeed:      make(chan Payload, 1000),
+		seekRequested: make(chan struct{}, 1),
+		eventUrl:      url,
+		session:       session,
+		eventYear:     eventYear,
+		cache:         cache,
+		client:        clientOrDefault(client),
+		speed:         1,
+	}
+}
+
+// CreateFsReplay - Replay the '<Topic>.jsonStream' files stored at the root of files instead of downloading them
+func CreateFsReplay(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	files fs.FS,
+	session Messages.SessionType,
+	eventYear int) *replay {
 
 	return &replay{
-		ctx:       ctx,
-		wg:        wg,
-		log:       log,
-		dataFeed:  make(chan Payload, 1000),
-		eventUrl:  url,
-		session:   session,
-		eventYear: eventYear,
-		cache:     cache,
+		ctx:           ctx,
+		wg:            wg,
+		log:           log,
+		dataFeed:      make(chan Payload, 1000),
+		seekRequested: make(chan struct{}, 1),
+		session:       session,
+		eventYear:     eventYear,
+		files:         files,
+		speed:         1,
 	}
 }
 
@@ -87,14 +166,18 @@ func (r *replay) Connect() (error, <-chan Payload) {
 
 	r.dataFiles = make([]fileInfo, 0)
 
-	for _, name := range OrderedFiles {
+	for _, name := range selectedTopics(r.topics) {
 
-		if (name == PositionFile || name == ContentStreamsFile) && r.eventYear <= 2018 {
-			continue
-		}
+		// Local files either exist or they don't so only skip the ones we know won't be available when
+		// they would need downloading
+		if r.files == nil {
+			if (name == PositionFile || name == ContentStreamsFile) && r.eventYear <= 2018 {
+				continue
+			}
 
-		if name == LapCountFile && !(r.session == Messages.RaceSession || r.session == Messages.SprintSession) {
-			continue
+			if name == LapCountFile && !(r.session == Messages.RaceSession || r.session == Messages.SprintSession) {
+				continue
+			}
 		}
 
 		// Often don't get this data for replays
@@ -102,19 +185,36 @@ func (r *replay) Connect() (error, <-chan Payload) {
 			continue
 		}
 
+		data, closer := r.open(name + ".jsonStream")
 		r.dataFiles = append(r.dataFiles, fileInfo{
 			name:         name,
-			data:         r.get(r.eventUrl + name + ".jsonStream"),
+			data:         data,
+			closer:       closer,
 			nextLine:     "",
 			nextLineTime: time.Time{},
 		})
 	}
 
-	go r.readEntries()
+	if r.batch {
+		go r.readAll()
+	} else {
+		go r.readEntries()
+	}
 
 	return nil, r.dataFeed
 }
 
+// SetBatch - Send all of the data in timestamp order as fast as it can be processed instead of in realtime. Must be
+// called before Connect.
+func (r *replay) SetBatch(batch bool) {
+	r.batch = batch
+}
+
+// SetTopics - Only read the files for topics instead of everything in OrderedFiles. Must be called before Connect.
+func (r *replay) SetTopics(topics []string) {
+	r.topics = topics
+}
+
 func (r *replay) IncrementTime(amount time.Duration) {
 	r.currentTimeLock.Lock()
 	defer r.currentTimeLock.Unlock()
@@ -122,14 +222,226 @@ func (r *replay) IncrementTime(amount time.Duration) {
 	r.currentTime = r.currentTime.Add(amount)
 }
 
-func (r *replay) JumpToStart() time.Time {
-	if r.raceStartTime.IsZero() {
-		return time.Time{}
+// SeekTo - Move the replay to target, which can be before the current time. If from isn't zero it is the time of a
+// keyframe before target and only the data after it is sent again. It returns straight away and the replay moves as
+// soon as it has finished sending the current second of data, so it is safe to call while the data is being read.
+func (r *replay) SeekTo(target time.Time, from time.Time) error {
+	r.currentTimeLock.Lock()
+	defer r.currentTimeLock.Unlock()
+
+	if r.batch {
+		return errors.New("batch replays can't be moved to a different time")
+	}
+
+	if r.finished {
+		return errors.New("the replay has finished")
+	}
+
+	r.seekTarget = target
+	r.seekFrom = from
+
+	select {
+	case r.seekRequested <- struct{}{}:
+	default:
+		// Already waiting to move
+	}
+	return nil
+}
+
+// LapStartTime - When the given lap started
+func (r *replay) LapStartTime(lap int) (time.Time, error) {
+	laps, err := r.lapIndex()
+	if err != nil {
+		return time.Time{}, err
 	}
 
+	for _, start := range laps {
+		if start.lap >= lap {
+			return start.timestamp, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("lap %d not found", lap)
+}
+
+// TimeWhenRemaining - When the session clock showed the given time remaining
+func (r *replay) TimeWhenRemaining(remaining time.Duration) (time.Time, error) {
+	clock, err := r.clockIndex()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return timeWhenRemaining(clock, remaining)
+}
+
+// Every lap count update in the session, read the first time it is needed
+func (r *replay) lapIndex() ([]lapStart, error) {
+	dataStartTime, _, err := r.findSessionTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	r.indexLock.Lock()
+	defer r.indexLock.Unlock()
+
+	if r.lapsRead {
+		return r.laps, nil
+	}
+
+	dataBuffer, closer := r.open(LapCountFile + ".jsonStream")
+	if dataBuffer == nil {
+		return nil, errors.New("there is no lap data for this session")
+	}
+	defer closer.Close()
+
+	for dataBuffer.Scan() {
+		timestamp, data, err := r.uncompressedDataTime(dataBuffer.Text(), dataStartTime)
+		if err != nil {
+			continue
+		}
+
+		var lapCount struct {
+			CurrentLap *int
+		}
+		if json.Unmarshal([]byte(data), &lapCount) != nil || lapCount.CurrentLap == nil {
+			continue
+		}
+
+		r.laps = append(r.laps, lapStart{lap: *lapCount.CurrentLap, timestamp: timestamp})
+	}
+
+	r.lapsRead = true
+	return r.laps, nil
+}
+
+// Every session clock update in the session, read the first time it is needed
+func (r *replay) clockIndex() ([]sessionClock, error) {
+	dataStartTime, _, err := r.findSessionTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	r.indexLock.Lock()
+	defer r.indexLock.Unlock()
+
+	if r.clockRead {
+		return r.clock, nil
+	}
+
+	dataBuffer, closer := r.open(ExtrapolatedClockFile + ".jsonStream")
+	if dataBuffer == nil {
+		return nil, errors.New("there is no session clock data for this session")
+	}
+	defer closer.Close()
+
+	var current sessionClock
+	for dataBuffer.Scan() {
+		timestamp, data, err := r.uncompressedDataTime(dataBuffer.Text(), dataStartTime)
+		if err != nil {
+			continue
+		}
+
+		var valid bool
+		current, valid = readSessionClock([]byte(data), timestamp, current)
+		if valid {
+			r.clock = append(r.clock, current)
+		}
+	}
+
+	r.clockRead = true
+	return r.clock, nil
+}
+
+// Reads an update to the session clock, anything it doesn't have carries on from previous. Without a time the clock
+// changed when the update was received at timestamp.
+func readSessionClock(data []byte, timestamp time.Time, previous sessionClock) (sessionClock, bool) {
+	var entry struct {
+		Utc           *string
+		Remaining     *string
+		Extrapolating *bool
+	}
+	if json.Unmarshal(data, &entry) != nil {
+		return previous, false
+	}
+
+	current := previous
+	current.utc = timestamp
+	if entry.Utc != nil {
+		utc, err := parseUtc(*entry.Utc)
+		if err != nil {
+			return previous, false
+		}
+		current.utc = utc
+	}
+	if current.utc.IsZero() {
+		return previous, false
+	}
+
+	if entry.Remaining != nil {
+		var hours, mins, secs int
+		_, err := fmt.Sscanf(*entry.Remaining, "%d:%d:%d", &hours, &mins, &secs)
+		if err != nil {
+			return previous, false
+		}
+		current.remaining = time.Duration(hours)*time.Hour + time.Duration(mins)*time.Minute + time.Duration(secs)*time.Second
+	}
+
+	if entry.Extrapolating != nil {
+		current.extrapolating = *entry.Extrapolating
+	}
+
+	return current, true
+}
+
+// When the clock showed remaining, counting down between updates while it was running
+func timeWhenRemaining(clock []sessionClock, remaining time.Duration) (time.Time, error) {
+	for x, current := range clock {
+		if x > 0 && clock[x-1].extrapolating {
+			previous := clock[x-1]
+			end := previous.remaining - current.utc.Sub(previous.utc)
+			if remaining <= previous.remaining && remaining >= end {
+				return previous.utc.Add(previous.remaining - remaining), nil
+			}
+		}
+
+		if current.remaining == remaining {
+			return current.utc, nil
+		}
+	}
+
+	if len(clock) > 0 {
+		last := clock[len(clock)-1]
+		if last.extrapolating && remaining <= last.remaining {
+			return last.utc.Add(last.remaining - remaining), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("the session clock never showed %s remaining", remaining)
+}
+
+func (r *replay) SetSpeed(speed float64) {
+	r.speedLock.Lock()
+	defer r.speedLock.Unlock()
+
+	r.speed = speed
+}
+
+// How often to send the next second of data for the current speed
+func (r *replay) tickInterval() time.Duration {
+	r.speedLock.Lock()
+	defer r.speedLock.Unlock()
+
+	return time.Duration(float64(time.Second) / r.speed)
+}
+
+func (r *replay) JumpToStart() time.Time {
 	r.currentTimeLock.Lock()
 	defer r.currentTimeLock.Unlock()
 
+	if r.raceStartTime.IsZero() {
+		return time.Time{}
+	}
+
 	r.currentTime = r.raceStartTime
 	// Clear this so we can only do it once and not travel back in time if requested multiple times
 	r.raceStartTime = time.Time{}
@@ -138,6 +450,9 @@ func (r *replay) JumpToStart() time.Time {
 }
 
 func (r *replay) readEntries() {
+	r.wg.Add(1)
+	defer r.wg.Done()
+	defer r.closeFiles()
 
 	dataStartTime, raceStartTime, err := r.findSessionTimes()
 	if err != nil {
@@ -147,18 +462,21 @@ func (r *replay) readEntries() {
 		return
 	}
 
+	r.currentTimeLock.Lock()
 	r.currentTime = dataStartTime
 	r.raceStartTime = raceStartTime
+	r.currentTimeLock.Unlock()
 
 	hasData := true
-	r.wg.Add(1)
-	defer r.wg.Done()
 
 	// Read drivers list and
 	for x := range r.dataFiles {
 		if r.dataFiles[x].name == DriverListFile {
 
-			r.dataFiles[x].data.Scan()
+			// A replay from local files might not have one
+			if r.dataFiles[x].data == nil || !r.dataFiles[x].data.Scan() {
+				break
+			}
 			line := r.dataFiles[x].data.Text()
 
 			r.dataFiles[x].nextLineTime, r.dataFiles[x].nextLine, err = r.uncompressedDataTime(line, dataStartTime)
@@ -176,14 +494,35 @@ func (r *replay) readEntries() {
 		}
 	}
 
-	ticker := time.NewTicker(time.Second)
+	// All of the data up to this time has been sent
+	var sentUpTo time.Time
+
+	tickInterval := r.tickInterval()
+	ticker := time.NewTicker(tickInterval)
 	for hasData {
 		select {
 		case <-r.ctx.Done():
 			ticker.Stop()
 			return
 
+		case <-r.seekRequested:
+			r.currentTimeLock.Lock()
+			seekTarget := r.seekTarget
+			seekFrom := r.seekFrom
+			r.seekTarget = time.Time{}
+			r.seekFrom = time.Time{}
+			r.currentTimeLock.Unlock()
+
+			if !seekTarget.IsZero() {
+				sentUpTo = r.seek(seekTarget, seekFrom, sentUpTo, dataStartTime)
+			}
+
 		case <-ticker.C:
+			if interval := r.tickInterval(); interval != tickInterval {
+				tickInterval = interval
+				ticker.Reset(tickInterval)
+			}
+
 			r.currentTimeLock.Lock()
 			currentTime := r.currentTime
 			r.currentTimeLock.Unlock()
@@ -213,6 +552,12 @@ func (r *replay) readEntries() {
 				}
 			}
 
+			sentUpTo = currentTime
+			r.dataFeed <- Payload{
+				Name:      KeyframeFile,
+				Timestamp: currentTime.Format("2006-01-02T15:04:05.999Z"),
+			}
+
 			currentTime = currentTime.Add(time.Second)
 			r.currentTimeLock.Lock()
 			// The user can increment the time independantly of us so check we are actually incrementing
@@ -225,11 +570,206 @@ func (r *replay) readEntries() {
 		}
 	}
 
+	r.currentTimeLock.Lock()
+	r.finished = true
+	r.currentTimeLock.Unlock()
+
 	r.dataFeed <- Payload{
 		Name: EndOfDataFile,
 	}
 }
 
+// Moves the replay to target. If the data after target has already been sent it starts again from the beginning, or
+// from the keyframe at from if there is one, so it can be sent again. Returns the time the data has now been sent up
+// to.
+func (r *replay) seek(target time.Time, from time.Time, sentUpTo time.Time, dataStartTime time.Time) time.Time {
+	if target.Before(dataStartTime) {
+		target = dataStartTime
+	}
+
+	seek := Payload{
+		Name:      SeekFile,
+		Timestamp: target.Format("2006-01-02T15:04:05.999Z"),
+	}
+
+	restart := target.Before(sentUpTo)
+	if restart {
+		for x := range r.dataFiles {
+			r.dataFiles[x].close()
+			r.dataFiles[x].data, r.dataFiles[x].closer = r.open(r.dataFiles[x].name + ".jsonStream")
+			r.dataFiles[x].nextLine = ""
+			r.dataFiles[x].nextLineTime = time.Time{}
+		}
+
+		seek.Data = []byte(SeekRestart)
+		sentUpTo = time.Time{}
+	}
+
+	// Skip everything the keyframe already has, going forwards this saves processing all the data in between
+	if !from.IsZero() && !from.After(target) && (restart || from.After(sentUpTo)) {
+		r.skipTo(from, dataStartTime)
+		seek.Data = []byte(from.Format("2006-01-02T15:04:05.999Z"))
+		sentUpTo = from
+	}
+
+	select {
+	case r.dataFeed <- seek:
+	case <-r.ctx.Done():
+	}
+
+	r.currentTimeLock.Lock()
+	r.currentTime = target
+	r.currentTimeLock.Unlock()
+
+	return sentUpTo
+}
+
+// Moves each file on past the data up to and including from without sending it
+func (r *replay) skipTo(from time.Time, dataStartTime time.Time) {
+	for x := range r.dataFiles {
+		file := &r.dataFiles[x]
+
+		if file.nextLine != "" && file.nextLineTime.After(from) {
+			continue
+		}
+
+		for r.readLine(file, dataStartTime) {
+			if file.nextLineTime.After(from) {
+				break
+			}
+		}
+	}
+}
+
+// Reads the next line of the file into nextLine, returns false when there is no more data
+func (r *replay) readLine(file *fileInfo, dataStartTime time.Time) bool {
+	file.nextLine = ""
+
+	if file.data == nil {
+		return false
+	}
+
+	splitData := r.uncompressedDataTime
+	if strings.HasSuffix(file.name, ".z") {
+		splitData = r.compressedDataTime
+	}
+
+	for file.data.Scan() {
+		timestamp, data, err := splitData(file.data.Text(), dataStartTime)
+		if err != nil {
+			continue
+		}
+
+		file.nextLineTime = timestamp
+		file.nextLine = data
+		return true
+	}
+
+	return false
+}
+
+// Sends the data from all of the files merged in timestamp order without waiting. When lines have the same timestamp
+// they are sent in the OrderedFiles order so the same data is always sent in the same order.
+func (r *replay) readAll() {
+	r.wg.Add(1)
+	defer r.wg.Done()
+	defer r.closeFiles()
+
+	dataStartTime, _, err := r.findSessionTimes()
+	if err != nil {
+		r.dataFeed <- Payload{
+			Name: EndOfDataFile,
+		}
+		return
+	}
+
+	r.currentTimeLock.Lock()
+	r.currentTime = dataStartTime
+	r.currentTimeLock.Unlock()
+
+	// Keyframes are still marked every second so the state can be saved as it would be in realtime
+	keyframe := dataStartTime
+
+	sent := r.merge(dataStartTime, func(file *fileInfo) bool {
+		for file.nextLineTime.After(keyframe) {
+			if !r.send(Payload{Name: KeyframeFile, Timestamp: keyframe.Format("2006-01-02T15:04:05.999Z")}) {
+				return false
+			}
+			keyframe = keyframe.Add(time.Second)
+		}
+
+		if !r.send(Payload{
+			Name:      file.name,
+			Data:      []byte(file.nextLine),
+			Timestamp: file.nextLineTime.Format("2006-01-02T15:04:05.999Z"),
+		}) {
+			return false
+		}
+
+		r.currentTimeLock.Lock()
+		r.currentTime = file.nextLineTime
+		r.currentTimeLock.Unlock()
+		return true
+	})
+	if !sent {
+		return
+	}
+
+	r.currentTimeLock.Lock()
+	r.finished = true
+	r.currentTimeLock.Unlock()
+
+	r.send(Payload{Name: EndOfDataFile})
+}
+
+func (r *replay) closeFiles() {
+	for x := range r.dataFiles {
+		r.dataFiles[x].close()
+	}
+}
+
+// Calls each with the file that has the next line until all of the files have been read, merging them in timestamp
+// order. When lines have the same timestamp the earlier file in dataFiles goes first. Returns false if each stopped it.
+func (r *replay) merge(dataStartTime time.Time, each func(file *fileInfo) bool) bool {
+	for x := range r.dataFiles {
+		r.readLine(&r.dataFiles[x], dataStartTime)
+	}
+
+	for {
+		next := -1
+		for x := range r.dataFiles {
+			if r.dataFiles[x].nextLine == "" {
+				continue
+			}
+
+			if next == -1 || r.dataFiles[x].nextLineTime.Before(r.dataFiles[next].nextLineTime) {
+				next = x
+			}
+		}
+
+		if next == -1 {
+			return true
+		}
+		file := &r.dataFiles[next]
+
+		if !each(file) {
+			return false
+		}
+
+		r.readLine(file, dataStartTime)
+	}
+}
+
+// Waits for the data to be taken, returns false if we are shutting down instead
+func (r *replay) send(data Payload) bool {
+	select {
+	case r.dataFeed <- data:
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
+
 func (r *replay) sim(
 	dataBuffer *bufio.Scanner,
 	currentRaceTime time.Time,
@@ -290,12 +830,29 @@ func (r *replay) sim(
 }
 
 func (r *replay) findSessionTimes() (dataStartTime time.Time, sessionStartTime time.Time, err error) {
-	dataBuffer := r.get(r.eventUrl + ExtrapolatedClockFile + ".jsonStream")
+	r.indexLock.Lock()
+	defer r.indexLock.Unlock()
+
+	if !r.dataStartTime.IsZero() {
+		return r.dataStartTime, r.sessionStartTime, nil
+	}
+
+	dataStartTime, sessionStartTime, err = r.readSessionTimes()
+	if err == nil {
+		r.dataStartTime = dataStartTime
+		r.sessionStartTime = sessionStartTime
+	}
+	return dataStartTime, sessionStartTime, err
+}
+
+func (r *replay) readSessionTimes() (dataStartTime time.Time, sessionStartTime time.Time, err error) {
+	dataBuffer, closer := r.open(ExtrapolatedClockFile + ".jsonStream")
 
 	if dataBuffer == nil {
 		r.log.Errorf("Unable to find session start time because file doesn't exist")
 		return time.Time{}, time.Time{}, errors.New("No file for session start time")
 	}
+	defer closer.Close()
 
 	dataBuffer.Scan()
 	line := dataBuffer.Text()
@@ -311,7 +868,11 @@ func (r *replay) findSessionTimes() (dataStartTime time.Time, sessionStartTime t
 		return time.Time{}, time.Time{}, err
 	}
 
-	dataBuffer.Scan()
+	// Recordings that start after the session has started won't have a second entry so there is no
+	// start to skip to
+	if !dataBuffer.Scan() {
+		return dataStartTime.Add(-offset), time.Time{}, nil
+	}
 	line = dataBuffer.Text()
 	sessionStartTime, _, err = r.timeFromSessionData(line)
 
@@ -322,6 +883,9 @@ func (r *replay) findSessionTimes() (dataStartTime time.Time, sessionStartTime t
 
 func (r *replay) timeFromSessionData(line string) (currentTime time.Time, offsetFromStart time.Duration, err error) {
 	timeEnd := strings.Index(line, "{")
+	if timeEnd < 12 {
+		return time.Time{}, 0, fmt.Errorf("invalid session start line: '%s'", line)
+	}
 	data := line[timeEnd:]
 	timestamp := line[timeEnd-12 : timeEnd]
 
@@ -348,6 +912,9 @@ func (r *replay) timeFromSessionData(line string) (currentTime time.Time, offset
 
 func (r *replay) uncompressedDataTime(data string, sessionStart time.Time) (timestamp time.Time, payload string, err error) {
 	timeEnd := strings.Index(data, "{")
+	if timeEnd < 12 {
+		return time.Time{}, "", fmt.Errorf("invalid data line: '%s'", data)
+	}
 
 	timestamp, err = r.raceTime(data[timeEnd-12:timeEnd], sessionStart)
 	if err != nil {
@@ -358,7 +925,11 @@ func (r *replay) uncompressedDataTime(data string, sessionStart time.Time) (time
 }
 
 func (r *replay) compressedDataTime(data string, sessionStart time.Time) (timestamp time.Time, payload string, err error) {
+	data = strings.TrimRight(data, "\r")
 	timeEnd := strings.Index(data, "\"")
+	if timeEnd < 12 || len(data) < timeEnd+2 {
+		return time.Time{}, "", fmt.Errorf("invalid compressed data line: '%s'", data)
+	}
 
 	timestamp, err = r.raceTime(data[timeEnd-12:timeEnd], sessionStart)
 	if err != nil {
@@ -382,7 +953,27 @@ func (r *replay) raceTime(value string, sessionStart time.Time) (time.Time, erro
 	return sessionStart.Add(timestamp), nil
 }
 
-func (r *replay) get(url string) *bufio.Scanner {
+// Opens the file to read a line at a time, the closer must be closed when finished with it. Both are nil if the file
+// isn't available.
+func (r *replay) open(name string) (*bufio.Scanner, io.Closer) {
+	if r.files == nil {
+		return r.get(r.eventUrl + name)
+	}
+
+	f, err := r.files.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			r.log.Warnf("Replay file '%s' doesn't exist", name)
+		} else {
+			r.log.Errorf("Replay file '%s': %s", name, err)
+		}
+		return nil, nil
+	}
+
+	return newLineScanner(f), f
+}
+
+func (r *replay) get(url string) (*bufio.Scanner, io.Closer) {
 
 	if len(r.cache) > 0 {
 		fileName := filepath.Base(url)
@@ -393,25 +984,28 @@ func (r *replay) get(url string) *bufio.Scanner {
 		f, err := os.Open(cachedFile)
 
 		if os.IsNotExist(err) {
-			f.Close()
-
 			var resp *http.Response
-			resp, err = http.Get(url)
+			resp, err = r.client.Get(url)
 			if err != nil {
 				r.log.Errorf("Replay url error for '%s': %s", url, err)
-				return nil
+				return nil, nil
 			}
 			defer resp.Body.Close()
 
+			if resp.StatusCode != http.StatusOK {
+				r.log.Errorf("Replay url '%s' returned: %s", url, resp.Status)
+				return nil, nil
+			}
+
 			if resp.ContentLength == int64(len(NotFoundResponse)) {
 				content, _ := io.ReadAll(resp.Body)
 				if string(content) == NotFoundResponse {
 					r.log.Errorf("Replay url not found '%s'", url)
-					return nil
+					return nil, nil
 				}
 			}
 
-			scanner := bufio.NewScanner(resp.Body)
+			scanner := newLineScanner(resp.Body)
 
 			err = os.MkdirAll(filepath.Dir(cachedFile), 0755)
 
@@ -428,25 +1022,38 @@ func (r *replay) get(url string) *bufio.Scanner {
 			f, err = os.Open(cachedFile)
 		}
 
-		return bufio.NewScanner(f)
+		if err != nil {
+			r.log.Errorf("Replay cached file '%s': %s", cachedFile, err)
+			return nil, nil
+		}
+
+		return newLineScanner(f), f
 	}
 
 	var resp *http.Response
-	resp, err := http.Get(url)
+	resp, err := r.client.Get(url)
 	if err != nil {
-		r.log.Errorf("Replay get url '%s': %s", err)
-		return nil
+		r.log.Errorf("Replay get url '%s': %s", url, err)
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		r.log.Errorf("Replay url '%s' returned: %s", url, resp.Status)
+		return nil, nil
 	}
-	// TODO - probably need to tidy this up but if we have no cache then we can't close it here or no data
-	//defer resp.Body.Close()
 
 	if resp.ContentLength == int64(len(NotFoundResponse)) {
 		content, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if string(content) == NotFoundResponse {
 			r.log.Errorf("Replay url not found '%s'", url)
-			return nil
+			return nil, nil
 		}
+
+		// Already read it all so carry on from what was read
+		return newLineScanner(strings.NewReader(string(content))), io.NopCloser(nil)
 	}
 
-	return bufio.NewScanner(resp.Body)
+	return newLineScanner(resp.Body), resp.Body
 }
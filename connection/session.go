@@ -0,0 +1,67 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// ReadSession - Read all the data from the '<Topic>.jsonStream' files stored at the root of files, merged into
+// timestamp order
+func ReadSession(files fs.FS, log *f1log.F1GopherLibLog) ([]Payload, error) {
+	r := CreateFsReplay(nil, nil, log, files, Messages.RaceSession, 0)
+
+	dataStartTime, _, err := r.findSessionTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.closeFiles()
+	for _, name := range OrderedFiles {
+		f, err := files.Open(name + ".jsonStream")
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		r.dataFiles = append(r.dataFiles, fileInfo{name: name, data: newLineScanner(f), closer: f})
+	}
+
+	var result []Payload
+	r.merge(dataStartTime, func(file *fileInfo) bool {
+		result = append(result, Payload{
+			Name:      file.name,
+			Data:      []byte(file.nextLine),
+			Timestamp: file.nextLineTime.Format("2006-01-02T15:04:05.999Z"),
+		})
+		return true
+	})
+
+	for _, file := range r.dataFiles {
+		if err = file.data.Err(); err != nil {
+			return nil, fmt.Errorf("reading '%s': %w", file.name, err)
+		}
+	}
+
+	return result, nil
+}
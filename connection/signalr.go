@@ -0,0 +1,133 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/f1gopher/signalr/v2"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"golang.org/x/sync/errgroup"
+)
+
+// LiveUrl - The classic SignalR endpoint for the live timing data
+const LiveUrl = "https://livetiming.formula1.com/signalr"
+
+// Classic SignalR
+type signalrClassic struct {
+	url    string
+	client *http.Client
+	log    *f1log.F1GopherLibLog
+}
+
+func (s *signalrClassic) subscribe(
+	ctx context.Context,
+	topics []string,
+	receive func(ctx context.Context, data Payload)) (*errgroup.Group, error) {
+
+	// The SignalR library adds a cookie jar to a client without one so give it a copy to avoid changing the one
+	// we were given
+	client := *s.client
+
+	// Prepare a SignalR client.
+	conn, err := signalr.Dial(
+		ctx,
+		s.url,
+		`[{"name":"streaming"}]`,
+		signalr.HTTPClient(&client),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hub := signalr.NewClient("streaming", conn)
+
+	errg, errgCtx := errgroup.WithContext(ctx)
+
+	// Register for the data before subscribing so the catchup returned by the subscribe isn't missed
+	stream, err := hub.Callback(errgCtx, "feed")
+	if err != nil {
+		hub.Close()
+		return nil, err
+	}
+
+	errg.Go(func() error { return hub.Run(errgCtx) })
+	errg.Go(func() error {
+		defer stream.Close()
+		return s.read(errgCtx, stream, receive)
+	})
+
+	err = hub.Invoke(errgCtx, "Subscribe", topics).Exec()
+	if err != nil {
+		hub.Close()
+		errg.Wait()
+		return nil, fmt.Errorf("subscribe failed: %w", err)
+	}
+
+	return errg, nil
+}
+
+func (s *signalrClassic) read(
+	ctx context.Context,
+	stream *signalr.CallbackStream,
+	receive func(ctx context.Context, data Payload)) error {
+
+	s.log.Info("Waiting for live data...")
+
+	for {
+		res := stream.ReadRaw()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// A message without a method means the stream has been closed underneath us
+		if len(res.Method) == 0 {
+			return errors.New("live data stream closed")
+		}
+
+		if res.Args == nil {
+			continue
+		}
+
+		if len(res.Args) == 3 {
+			data := Payload{}
+			abc, _ := res.Args[0].MarshalJSON()
+			data.Name = string(abc[1 : len(abc)-1])
+			abc, _ = res.Args[1].MarshalJSON()
+			if abc[0] == '"' {
+				data.Data = abc[1 : len(abc)-1]
+			} else {
+				data.Data = abc
+			}
+			abc, _ = res.Args[2].MarshalJSON()
+			data.Timestamp = string(abc[1 : len(abc)-1])
+
+			receive(ctx, data)
+		} else if len(res.Args) == 1 {
+			abc, _ := res.Args[0].MarshalJSON()
+			receive(ctx, Payload{
+				Name: CatchupFile,
+				Data: abc,
+			})
+		} else {
+			s.log.Errorf("There is an unhandled number of arguments for live data: %d, dropping data", len(res.Args))
+		}
+	}
+}
@@ -0,0 +1,461 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package connection
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"golang.org/x/sync/errgroup"
+)
+
+// LiveCoreUrl - The SignalR Core hub for the live timing data
+const LiveCoreUrl = "https://livetiming.formula1.com/signalrcore"
+
+// Every SignalR Core message is json terminated by the record separator character. A websocket frame can hold
+// more than one message.
+const coreRecordSeparator = 0x1e
+
+// SignalR Core message types
+const (
+	coreInvocation = 1
+	coreCompletion = 3
+	corePing       = 6
+	coreClose      = 7
+)
+
+const corePingInterval = 15 * time.Second
+const coreTimeout = 30 * time.Second
+
+// Number of times the negotiate can redirect us to another server
+const coreMaxRedirects = 5
+
+const coreSubscribeId = "0"
+
+type coreMessage struct {
+	Type         int               `json:"type"`
+	InvocationId string            `json:"invocationId,omitempty"`
+	Target       string            `json:"target,omitempty"`
+	Arguments    []json.RawMessage `json:"arguments,omitempty"`
+	Result       json.RawMessage   `json:"result,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+type coreNegotiation struct {
+	ConnectionId        string `json:"connectionId"`
+	ConnectionToken     string `json:"connectionToken"`
+	NegotiateVersion    int    `json:"negotiateVersion"`
+	AvailableTransports []struct {
+		Transport string `json:"transport"`
+	} `json:"availableTransports"`
+	Url         string `json:"url"`
+	AccessToken string `json:"accessToken"`
+	Error       string `json:"error"`
+}
+
+// SignalR Core using the json protocol over a websocket
+type signalrCore struct {
+	url    string
+	client *http.Client
+	log    *f1log.F1GopherLibLog
+}
+
+// Websocket connections only allow one writer at a time
+type coreSocket struct {
+	conn *websocket.Conn
+	lock sync.Mutex
+}
+
+func (c *coreSocket) send(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(coreTimeout))
+	return c.conn.WriteMessage(websocket.TextMessage, append(data, coreRecordSeparator))
+}
+
+func (s *signalrCore) subscribe(
+	ctx context.Context,
+	topics []string,
+	receive func(ctx context.Context, data Payload)) (*errgroup.Group, error) {
+
+	hubUrl, header, negotiation, err := s.negotiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	socket, err := s.dial(ctx, hubUrl, header, negotiation)
+	if err != nil {
+		return nil, err
+	}
+
+	errg, errgCtx := errgroup.WithContext(ctx)
+	subscribed := make(chan error, 1)
+
+	errg.Go(func() error { return s.read(errgCtx, socket, subscribed, receive) })
+	errg.Go(func() error { return s.keepAlive(errgCtx, socket) })
+
+	subscribeTopics, err := json.Marshal(topics)
+	if err == nil {
+		err = socket.send(coreMessage{
+			Type:         coreInvocation,
+			InvocationId: coreSubscribeId,
+			Target:       "Subscribe",
+			Arguments:    []json.RawMessage{subscribeTopics},
+		})
+	}
+	if err != nil {
+		socket.conn.Close()
+		errg.Wait()
+		return nil, fmt.Errorf("subscribe failed: %w", err)
+	}
+
+	select {
+	case err = <-subscribed:
+	case <-errgCtx.Done():
+		err = errg.Wait()
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	if err != nil {
+		socket.conn.Close()
+		errg.Wait()
+		return nil, fmt.Errorf("subscribe failed: %w", err)
+	}
+
+	return errg, nil
+}
+
+// Asks the server for a connection, following any redirects to other servers
+func (s *signalrCore) negotiate(ctx context.Context) (*url.URL, http.Header, coreNegotiation, error) {
+	hubUrl, err := url.Parse(s.url)
+	if err != nil {
+		return nil, nil, coreNegotiation{}, err
+	}
+	header := http.Header{}
+
+	for redirects := 0; redirects <= coreMaxRedirects; redirects++ {
+		negotiateUrl := *hubUrl
+		negotiateUrl.Path = strings.TrimSuffix(negotiateUrl.Path, "/") + "/negotiate"
+		query := negotiateUrl.Query()
+		query.Set("negotiateVersion", "1")
+		negotiateUrl.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, negotiateUrl.String(), nil)
+		if err != nil {
+			return nil, nil, coreNegotiation{}, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, nil, coreNegotiation{}, fmt.Errorf("negotiate failed: %w", err)
+		}
+
+		var result coreNegotiation
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, nil, coreNegotiation{}, fmt.Errorf("negotiate failed: %s", resp.Status)
+		}
+		if err != nil {
+			return nil, nil, coreNegotiation{}, fmt.Errorf("negotiate returned invalid data: %w", err)
+		}
+		if len(result.Error) > 0 {
+			return nil, nil, coreNegotiation{}, fmt.Errorf("negotiate failed: %s", result.Error)
+		}
+
+		// Load balancers use cookies to send the websocket to the same server as the negotiate
+		var cookies []string
+		for _, cookie := range resp.Cookies() {
+			cookies = append(cookies, cookie.Name+"="+cookie.Value)
+		}
+		if len(cookies) > 0 {
+			header.Set("Cookie", strings.Join(cookies, "; "))
+		}
+
+		if len(result.Url) == 0 {
+			return hubUrl, header, result, nil
+		}
+
+		hubUrl, err = url.Parse(result.Url)
+		if err != nil {
+			return nil, nil, coreNegotiation{}, fmt.Errorf("negotiate redirected to invalid url '%s': %w", result.Url, err)
+		}
+		header = http.Header{}
+		if len(result.AccessToken) > 0 {
+			header.Set("Authorization", "Bearer "+result.AccessToken)
+		}
+	}
+
+	return nil, nil, coreNegotiation{}, errors.New("negotiate failed: too many redirects")
+}
+
+// The websocket doesn't go through the client so it uses the same proxy, TLS and dial settings as the client's
+// transport instead
+func websocketDialer(client *http.Client) websocket.Dialer {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: coreTimeout,
+	}
+
+	roundTripper := client.Transport
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+
+	transport, ok := roundTripper.(*http.Transport)
+	if !ok {
+		// Only the standard transport's settings can be copied
+		return dialer
+	}
+
+	dialer.Proxy = transport.Proxy
+	dialer.TLSClientConfig = transport.TLSClientConfig
+	dialer.NetDialContext = transport.DialContext
+	dialer.NetDialTLSContext = transport.DialTLSContext
+	return dialer
+}
+
+// Opens the websocket and agrees to use the json protocol
+func (s *signalrCore) dial(
+	ctx context.Context,
+	hubUrl *url.URL,
+	header http.Header,
+	negotiation coreNegotiation) (*coreSocket, error) {
+
+	if len(negotiation.AvailableTransports) > 0 {
+		supported := false
+		for _, transport := range negotiation.AvailableTransports {
+			if transport.Transport == "WebSockets" {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return nil, errors.New("server does not support websockets")
+		}
+	}
+
+	socketUrl := *hubUrl
+	switch socketUrl.Scheme {
+	case "https":
+		socketUrl.Scheme = "wss"
+	case "http":
+		socketUrl.Scheme = "ws"
+	}
+
+	id := negotiation.ConnectionToken
+	if negotiation.NegotiateVersion == 0 || len(id) == 0 {
+		id = negotiation.ConnectionId
+	}
+	if len(id) > 0 {
+		query := socketUrl.Query()
+		query.Set("id", id)
+		socketUrl.RawQuery = query.Encode()
+	}
+
+	dialer := websocketDialer(s.client)
+	conn, _, err := dialer.DialContext(ctx, socketUrl.String(), header)
+	if err != nil {
+		return nil, fmt.Errorf("websocket connect failed: %w", err)
+	}
+
+	socket := &coreSocket{conn: conn}
+
+	err = socket.send(map[string]any{"protocol": "json", "version": 1})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(coreTimeout))
+	_, frame, err := conn.ReadMessage()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var response struct {
+		Error string `json:"error"`
+	}
+	handshake, _, _ := bytes.Cut(frame, []byte{coreRecordSeparator})
+	err = json.Unmarshal(handshake, &response)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake returned invalid data: %w", err)
+	}
+	if len(response.Error) > 0 {
+		conn.Close()
+		return nil, fmt.Errorf("handshake failed: %s", response.Error)
+	}
+
+	return socket, nil
+}
+
+func (s *signalrCore) read(
+	ctx context.Context,
+	socket *coreSocket,
+	subscribed chan<- error,
+	receive func(ctx context.Context, data Payload)) error {
+
+	s.log.Info("Waiting for live data...")
+
+	for {
+		// The server pings regularly so if we don't hear anything the connection has gone
+		socket.conn.SetReadDeadline(time.Now().Add(coreTimeout))
+
+		_, frame, err := socket.conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, record := range bytes.Split(frame, []byte{coreRecordSeparator}) {
+			if len(record) == 0 {
+				continue
+			}
+
+			var msg coreMessage
+			err = json.Unmarshal(record, &msg)
+			if err != nil {
+				s.log.Errorf("Invalid live data message '%s': %v", record, err)
+				continue
+			}
+
+			switch msg.Type {
+			case coreInvocation:
+				if !strings.EqualFold(msg.Target, "feed") {
+					continue
+				}
+
+				data, err := corePayload(msg.Arguments)
+				if err != nil {
+					s.log.Errorf("Invalid live data, dropping data: %v", err)
+					continue
+				}
+
+				receive(ctx, data)
+
+			case coreCompletion:
+				if msg.InvocationId != coreSubscribeId {
+					continue
+				}
+
+				if len(msg.Error) > 0 {
+					err = errors.New(msg.Error)
+					subscribed <- err
+					return err
+				}
+
+				if len(msg.Result) > 0 && !bytes.Equal(msg.Result, []byte("null")) {
+					receive(ctx, Payload{
+						Name: CatchupFile,
+						Data: msg.Result,
+					})
+				}
+				subscribed <- nil
+
+			case coreClose:
+				if len(msg.Error) > 0 {
+					return fmt.Errorf("server closed the connection: %s", msg.Error)
+				}
+				return errors.New("server closed the connection")
+
+			default:
+				// Pings only keep the connection alive and nothing else is used
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Pings the server so it knows we are still here and closes the connection when we are done
+func (s *signalrCore) keepAlive(ctx context.Context, socket *coreSocket) error {
+	ticker := time.NewTicker(corePingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			socket.send(coreMessage{Type: coreClose})
+			return socket.conn.Close()
+
+		case <-ticker.C:
+			err := socket.send(coreMessage{Type: corePing})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// The feed arguments are the topic, data and timestamp
+func corePayload(args []json.RawMessage) (Payload, error) {
+	if len(args) != 3 {
+		return Payload{}, fmt.Errorf("unhandled number of arguments: %d", len(args))
+	}
+
+	var data Payload
+	err := json.Unmarshal(args[0], &data.Name)
+	if err != nil {
+		return Payload{}, fmt.Errorf("invalid topic: %w", err)
+	}
+
+	// Compressed data is a string but everything else is json
+	if len(args[1]) > 0 && args[1][0] == '"' {
+		var value string
+		err = json.Unmarshal(args[1], &value)
+		if err != nil {
+			return Payload{}, fmt.Errorf("invalid data for '%s': %w", data.Name, err)
+		}
+		data.Data = []byte(value)
+	} else {
+		data.Data = args[1]
+	}
+
+	err = json.Unmarshal(args[2], &data.Timestamp)
+	if err != nil {
+		return Payload{}, fmt.Errorf("invalid timestamp for '%s': %w", data.Name, err)
+	}
+
+	return data, nil
+}
@@ -20,7 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -43,12 +46,18 @@ type F1GopherLib interface {
 	Weather() <-chan Messages.Weather
 	RaceControlMessages() <-chan Messages.RaceControlMessage
 	Timing() <-chan Messages.Timing
+	TimingDeltas() <-chan Messages.TimingDelta
 	Event() <-chan Messages.Event
 	Telemetry() <-chan Messages.Telemetry
 	Location() <-chan Messages.Location
 	Time() <-chan Messages.EventTime
 	Radio() <-chan Messages.Radio
 	Drivers() <-chan Messages.Drivers
+	ConnectionStatus() <-chan Messages.ConnectionStatus
+	Stream() <-chan Messages.Envelope
+	Snapshot() Messages.Snapshot
+	Dropped() map[Messages.StreamType]uint64
+	Subscribe(streamType Messages.StreamType, drivers []int, handler Handler) (func(), error)
 
 	SelectTelemetrySources(drivers []int)
 
@@ -57,12 +66,23 @@ type F1GopherLib interface {
 	SkipToSessionStart()
 	TogglePause()
 	IsPaused() bool
+	SetSpeed(speed float64)
+	Speed() float64
+	SeekTo(target time.Time) error
+	SeekToLap(lap int) error
+	SeekToRemaining(remaining time.Duration) error
+	IncrementDelay(delay time.Duration)
+	DecrementDelay(delay time.Duration)
+	Delay() time.Duration
+	CatchUpToLive() error
+	BehindLive() time.Duration
 
 	Close()
 }
 
 type f1gopherlib struct {
 	archive string
+	options options
 
 	session           Messages.SessionType
 	name              string
@@ -75,6 +95,16 @@ type f1gopherlib struct {
 	connection   connection.Connection
 	dataHandler  *parser.Parser
 	replayTiming flowControl.Flow
+	speed        float64
+	speedLock    sync.Mutex
+	isLive       bool
+
+	// One seek at a time so each waits for its own to be applied
+	seekLock sync.Mutex
+
+	// The parser state saved through a replay and where to save it when closed
+	keyframes    *parser.Keyframes
+	keyframeFile string
 
 	weather             chan Messages.Weather
 	raceControlMessages chan Messages.RaceControlMessage
@@ -85,6 +115,11 @@ type f1gopherlib struct {
 	eventTime           chan Messages.EventTime
 	radio               chan Messages.Radio
 	drivers             chan Messages.Drivers
+	connectionStatus    chan Messages.ConnectionStatus
+	stream              chan Messages.Envelope
+	timingDeltas        chan Messages.TimingDelta
+
+	subscriptions subscriptions
 
 	ctxShutdown context.CancelFunc
 	ctx         context.Context
@@ -102,6 +137,20 @@ const locationChannelSize = 1000
 const eventTimeChannelSize = 10
 const radioChannelSize = 100
 const driversChannelSize = 100
+const connectionStatusChannelSize = 10
+const streamChannelSize = 10000
+const subscriptionChannelSize = 10000
+
+// A live connection that keeps everything received so it can go back in time
+type liveBuffer interface {
+	Latest() time.Time
+}
+
+// Saved alongside the cached session data
+const keyframesFileName = "Keyframes.json"
+
+// DefaultBaseUrl - The official live timing server that the static session data is downloaded from
+const DefaultBaseUrl = "https://livetiming.formula1.com/static/"
 
 var f1Log = f1log.CreateLog()
 
@@ -142,7 +191,8 @@ func CreateRaceEvent(
 	}
 
 	urlName = fmt.Sprintf(
-		"https://livetiming.formula1.com/static/%d/%d-%02d-%02d_%s_Grand_Prix/%d-%02d-%02d_%s/",
+		"%s%d/%d-%02d-%02d_%s_Grand_Prix/%d-%02d-%02d_%s/",
+		DefaultBaseUrl,
 		raceTime.Year(),
 		raceTime.Year(),
 		raceTime.Month(),
@@ -196,93 +246,134 @@ func (r *RaceEvent) Url() string {
 	return r.urlName
 }
 
+// UrlFrom - The url for the event when the data is hosted somewhere other than the official server
+func (r *RaceEvent) UrlFrom(baseUrl string) string {
+	if !strings.HasPrefix(r.urlName, DefaultBaseUrl) {
+		return r.urlName
+	}
+
+	if !strings.HasSuffix(baseUrl, "/") {
+		baseUrl = baseUrl + "/"
+	}
+
+	return baseUrl + strings.TrimPrefix(r.urlName, DefaultBaseUrl)
+}
+
 // CreateLiveRealtime - Create a live session that will be updated in realtime as the data comes in
 // without a need for archiving or caching and no ticker.
 //
 // This endpoint also supports external context.
-func CreateLiveRealtime(requestedData parser.DataSource) (F1GopherLib, error) {
-	currentEvent, exists := liveEvent()
+func CreateLiveRealtime(requestedData parser.DataSource, opts ...Option) (F1GopherLib, error) {
+	settings := createOptions(opts)
 
-	// No event happening or about to happen so nothing we can do
-	if !exists {
-		return nil, errors.New("No live event currently happening")
+	var currentEvent RaceEvent
+	if settings.liveEvent != nil {
+		currentEvent = *settings.liveEvent
+	} else {
+		var exists bool
+		currentEvent, exists = liveEvent()
+
+		// No event happening or about to happen so nothing we can do
+		if !exists {
+			return nil, errors.New("No live event currently happening")
+		}
 	}
 
 	f1Log.Infof("Creating live session for: %v", currentEvent.string())
 
-	data := f1gopherlib{
-		weather:             make(chan Messages.Weather, weatherChannelSize),
-		raceControlMessages: make(chan Messages.RaceControlMessage, rcmChannelSize),
-		timing:              make(chan Messages.Timing, timingChannelSize),
-		event:               make(chan Messages.Event, eventChannelSize),
-		telemetry:           make(chan Messages.Telemetry, telemetryChannelSize),
-		location:            make(chan Messages.Location, locationChannelSize),
-		eventTime:           make(chan Messages.EventTime, eventTimeChannelSize),
-		radio:               make(chan Messages.Radio, radioChannelSize),
-		drivers:             make(chan Messages.Drivers, driversChannelSize),
-
-		session:           currentEvent.Type,
-		name:              currentEvent.Name,
-		timezone:          currentEvent.Timezone(),
-		sessionStart:      currentEvent.EventTime,
-		track:             currentEvent.TrackName,
-		trackYear:         currentEvent.TrackYearCreated,
-		timeLostInPitlane: currentEvent.TimeLostInPitlane,
-	}
-	data.ctx, data.ctxShutdown = context.WithCancel(context.Background())
+	data := createSession(currentEvent, opts)
 
 	err := data.connectLiveRealtime(requestedData, currentEvent)
 	if err != nil {
 		return nil, err
 	}
-	return &data, nil
+	return data, nil
 }
 
+// CreateDebugReplay - Replay an archive recorded from a live session. The event information is read from the
+// archive, older archives that don't contain it will have an empty event.
 func CreateDebugReplay(
 	requestedData parser.DataSource,
 	replayFile string,
-	dataFlow flowControl.FlowType) (F1GopherLib, error) {
+	dataFlow flowControl.FlowType,
+	opts ...Option) (F1GopherLib, error) {
 
-	// TODO - read event info from debug file
-	event := RaceEvent{}
+	header, err := connection.ReadArchiveHeader(replayFile)
+	if err != nil {
+		return nil, err
+	}
+	event := eventFromArchive(header)
 
 	f1Log.Infof("Creating live replay session for: %v", event.string())
 
-	data := f1gopherlib{
-		weather:             make(chan Messages.Weather, weatherChannelSize),
-		raceControlMessages: make(chan Messages.RaceControlMessage, rcmChannelSize),
-		timing:              make(chan Messages.Timing, timingChannelSize),
-		event:               make(chan Messages.Event, eventChannelSize),
-		telemetry:           make(chan Messages.Telemetry, telemetryChannelSize),
-		location:            make(chan Messages.Location, locationChannelSize),
-		eventTime:           make(chan Messages.EventTime, eventTimeChannelSize),
-		radio:               make(chan Messages.Radio, radioChannelSize),
-		drivers:             make(chan Messages.Drivers, driversChannelSize),
-		session:             event.Type,
-		name:                event.Name,
-		timezone:            event.Timezone(),
-		sessionStart:        event.EventTime,
-		track:               event.TrackName,
-		trackYear:           event.TrackYearCreated,
-		timeLostInPitlane:   event.TimeLostInPitlane,
-	}
-	data.ctx, data.ctxShutdown = context.WithCancel(context.Background())
+	data := createSession(event, opts)
 
-	err := data.connectDebugReplay(requestedData, replayFile, event, dataFlow)
+	err = data.connectDebugReplay(requestedData, replayFile, event, dataFlow)
 	if err != nil {
 		return nil, err
 	}
-	return &data, nil
+	return data, nil
 }
 
 func CreateReplay(
 	requestedData parser.DataSource,
 	event RaceEvent,
 	cache string,
-	dataFlow flowControl.FlowType) (F1GopherLib, error) {
+	dataFlow flowControl.FlowType,
+	opts ...Option) (F1GopherLib, error) {
 
 	f1Log.Infof("Creating replay session for: %v", event.string())
 
+	data := createSession(event, opts)
+
+	err := data.connectReplay(requestedData, event, cache, dataFlow)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CreateReplayFromFS - Replay a session from the '<Topic>.jsonStream' files (and 'TeamRadio/' audio) stored
+// at the root of files. This can be a directory, an embedded filesystem or a zip file. Nothing is downloaded
+// so the event is only used to describe the session.
+func CreateReplayFromFS(
+	requestedData parser.DataSource,
+	files fs.FS,
+	event RaceEvent,
+	dataFlow flowControl.FlowType,
+	opts ...Option) (F1GopherLib, error) {
+
+	f1Log.Infof("Creating file replay session for: %v", event.string())
+
+	data := createSession(event, opts)
+
+	err := data.connectFsReplay(requestedData, files, event, dataFlow)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CreateReplayFromDir - Replay a session from a directory of '<Topic>.jsonStream' files
+func CreateReplayFromDir(
+	requestedData parser.DataSource,
+	dir string,
+	event RaceEvent,
+	dataFlow flowControl.FlowType,
+	opts ...Option) (F1GopherLib, error) {
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay path '%s' is not a directory", dir)
+	}
+
+	return CreateReplayFromFS(requestedData, os.DirFS(dir), event, dataFlow, opts...)
+}
+
+func createSession(event RaceEvent, opts []Option) *f1gopherlib {
 	data := f1gopherlib{
 		weather:             make(chan Messages.Weather, weatherChannelSize),
 		raceControlMessages: make(chan Messages.RaceControlMessage, rcmChannelSize),
@@ -293,6 +384,9 @@ func CreateReplay(
 		eventTime:           make(chan Messages.EventTime, eventTimeChannelSize),
 		radio:               make(chan Messages.Radio, radioChannelSize),
 		drivers:             make(chan Messages.Drivers, driversChannelSize),
+		connectionStatus:    make(chan Messages.ConnectionStatus, connectionStatusChannelSize),
+		stream:              make(chan Messages.Envelope, streamChannelSize),
+		timingDeltas:        make(chan Messages.TimingDelta, timingChannelSize),
 		session:             event.Type,
 		name:                event.Name,
 		timezone:            event.Timezone(),
@@ -300,53 +394,79 @@ func CreateReplay(
 		track:               event.TrackName,
 		trackYear:           event.TrackYearCreated,
 		timeLostInPitlane:   event.TimeLostInPitlane,
+		options:             createOptions(opts),
+		speed:               1,
 	}
 	data.ctx, data.ctxShutdown = context.WithCancel(context.Background())
 
-	err := data.connectReplay(requestedData, event, cache, dataFlow)
-	if err != nil {
-		return nil, err
-	}
-	return &data, nil
+	return &data
 }
 
 func (f *f1gopherlib) connectLiveRealtime(requestedData parser.DataSource, event RaceEvent) error {
-	f.connection = connection.CreateLive(f.ctx, &f.wg, f1Log)
+	live := connection.CreateLiveFrom(f.ctx, &f.wg, f1Log, f.options.liveUrlOr(connection.LiveUrl), f.options.httpClient)
+	if f.options.signalrCore {
+		live = connection.CreateLiveCore(f.ctx, &f.wg, f1Log, f.options.liveUrlOr(connection.LiveCoreUrl), f.options.httpClient)
+	}
+	live.SetStatusOutput(f.connectionStatus)
+
+	// Recordings keep everything so they can be replayed with any data
+	if len(f.options.archiveFile) == 0 && len(f.options.recordCache) == 0 {
+		live.SetTopics(parser.Topics(requestedData))
+	}
+
+	if len(f.options.archiveFile) > 0 {
+		err := live.ArchiveTo(f.options.archiveFile, archiveHeader(event))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(f.options.recordCache) > 0 {
+		// Record into the same place a replay would cache the data so it can be replayed without downloading
+		recordPath := f.cachePath(f.options.recordCache, event)
+		recordAssets := connection.CreateAssetStore(event.UrlFrom(f.options.baseUrl), recordPath, f1Log, f.options.httpClient)
+
+		err := live.RecordTo(recordPath, recordAssets)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.connection = live
+	f.isLive = true
+
+	if f.options.backfill {
+		history := connection.CreateReplay(
+			f.ctx,
+			&f.wg,
+			f1Log,
+			event.UrlFrom(f.options.baseUrl),
+			event.Type,
+			event.RaceTime.Year(),
+			"",
+			f.options.httpClient)
+		history.SetTopics(parser.Topics(requestedData))
+		f.connection = connection.CreateBackfill(f.ctx, &f.wg, f1Log, f.connection, history)
+	}
+
+	// Going back in time needs the data sending at the right time instead of as soon as it arrives
+	flowType := flowControl.StraightThrough
+	if f.options.dvr {
+		f.connection = connection.CreateDvr(f.ctx, &f.wg, f1Log, f.connection, f.options.dvrDir)
+		flowType = flowControl.Realtime
+
+		// The recording is removed on shutdown so there is nothing to save the keyframes for
+		f.createKeyframes(requestedData, "")
+	}
+
 	err, dataChannel := f.connection.Connect()
 	if err != nil {
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		flowControl.StraightThrough,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
-
-	assetStore := connection.CreateAssetStore(event.Url(), "", f1Log)
-
-	f.dataHandler = parser.Create(
-		f.ctx,
-		&f.wg,
-		requestedData,
-		dataChannel,
-		f.replayTiming,
-		assetStore,
-		Messages.RaceSession,
-		f1Log,
-		event.Timezone())
+	assetStore := connection.CreateAssetStore(event.UrlFrom(f.options.baseUrl), "", f1Log, f.options.httpClient)
 
-	go f.dataHandler.Process()
-	go f.replayTiming.Run()
-	// TODO - connect to live dataf.connection = connection.CreateLive(f.ctx, &f.wg, f1Log)
+	f.startProcessing(requestedData, dataChannel, flowType, assetStore, event.Type, event.Timezone())
 	return nil
 }
 
@@ -354,50 +474,35 @@ func (f *f1gopherlib) connectLive(requestedData parser.DataSource, archiveFile s
 
 	cache = f.cachePath(cache, event)
 
+	live := connection.CreateLiveFrom(f.ctx, &f.wg, f1Log, f.options.liveUrlOr(connection.LiveUrl), f.options.httpClient)
+	if f.options.signalrCore {
+		live = connection.CreateLiveCore(f.ctx, &f.wg, f1Log, f.options.liveUrlOr(connection.LiveCoreUrl), f.options.httpClient)
+	}
+	live.SetStatusOutput(f.connectionStatus)
+
+	// Archives keep everything so they can be replayed with any data
 	if len(archiveFile) == 0 {
-		f.connection = connection.CreateLive(f.ctx, &f.wg, f1Log)
-	} else {
-		var connErr error
-		f.connection, connErr = connection.CreateArchivingLive(f.ctx, &f.wg, f1Log, archiveFile)
+		live.SetTopics(parser.Topics(requestedData))
+	}
+
+	if len(archiveFile) > 0 {
+		connErr := live.ArchiveTo(archiveFile, archiveHeader(event))
 		if connErr != nil {
 			return connErr
 		}
 	}
 
+	f.connection = live
+	f.isLive = true
+
 	err, dataChannel := f.connection.Connect()
 	if err != nil {
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		flowControl.Realtime,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
-
-	assetStore := connection.CreateAssetStore(event.Url(), cache, f1Log)
+	assetStore := connection.CreateAssetStore(event.UrlFrom(f.options.baseUrl), cache, f1Log, f.options.httpClient)
 
-	f.dataHandler = parser.Create(
-		f.ctx,
-		&f.wg,
-		requestedData,
-		dataChannel,
-		f.replayTiming,
-		assetStore,
-		Messages.RaceSession,
-		f1Log,
-		event.Timezone())
-
-	go f.dataHandler.Process()
-	go f.replayTiming.Run()
+	f.startProcessing(requestedData, dataChannel, flowControl.Realtime, assetStore, event.Type, event.Timezone())
 
 	return nil
 }
@@ -415,71 +520,117 @@ func (f *f1gopherlib) connectDebugReplay(
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		dataFlow,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
-
 	// Don't use a cache for debug replays because we don't always know the event yet to give it a useful folder name
-	assetStore := connection.CreateAssetStore(event.Url(), "", f1Log)
+	assetStore := connection.CreateAssetStore(event.UrlFrom(f.options.baseUrl), "", f1Log, f.options.httpClient)
 
-	f.dataHandler = parser.Create(
+	f.startProcessing(requestedData, dataChannel, dataFlow, assetStore, event.Type, event.Timezone())
+
+	return nil
+}
+
+func (f *f1gopherlib) connectReplay(
+	requestedData parser.DataSource,
+	event RaceEvent,
+	cache string,
+	dataFlow flowControl.FlowType) error {
+
+	url := event.UrlFrom(f.options.baseUrl)
+	keyframeFile := f.options.keyframeFile
+
+	// Without a cache folder nothing is saved
+	if len(cache) > 0 {
+		cache = f.cachePath(cache, event)
+
+		if len(keyframeFile) == 0 {
+			keyframeFile = filepath.Join(cache, keyframesFileName)
+		}
+	}
+
+	replay := connection.CreateReplay(
 		f.ctx,
 		&f.wg,
-		requestedData,
-		dataChannel,
-		f.replayTiming,
-		assetStore,
-		event.Type,
 		f1Log,
-		event.Timezone())
+		url,
+		event.Type,
+		event.RaceTime.Year(),
+		cache,
+		f.options.httpClient)
+	replay.SetTopics(parser.Topics(requestedData))
+	dataFlow = f.batch(replay, dataFlow)
 
-	go f.dataHandler.Process()
-	go f.replayTiming.Run()
+	f.connection = replay
+	err, dataChannel := f.connection.Connect()
+
+	if err != nil {
+		return err
+	}
+
+	assetStore := connection.CreateAssetStore(url, cache, f1Log, f.options.httpClient)
+
+	f.createKeyframes(requestedData, keyframeFile)
+	f.startProcessing(requestedData, dataChannel, dataFlow, assetStore, event.Type, event.Timezone())
 
 	return nil
 }
 
-func (f *f1gopherlib) connectReplay(
+func (f *f1gopherlib) connectFsReplay(
 	requestedData parser.DataSource,
+	files fs.FS,
 	event RaceEvent,
-	cache string,
 	dataFlow flowControl.FlowType) error {
 
-	url := event.Url()
-	cache = f.cachePath(cache, event)
+	replay := connection.CreateFsReplay(f.ctx, &f.wg, f1Log, files, event.Type, event.RaceTime.Year())
+	replay.SetTopics(parser.Topics(requestedData))
+	dataFlow = f.batch(replay, dataFlow)
 
-	f.connection = connection.CreateReplay(f.ctx, &f.wg, f1Log, url, event.Type, event.RaceTime.Year(), cache)
+	f.connection = replay
 	err, dataChannel := f.connection.Connect()
 
 	if err != nil {
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		dataFlow,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
-
-	assetStore := connection.CreateAssetStore(event.Url(), cache, f1Log)
+	assetStore := connection.CreateFsAssetStore(files, f1Log)
+
+	f.createKeyframes(requestedData, f.options.keyframeFile)
+	f.startProcessing(requestedData, dataChannel, dataFlow, assetStore, event.Type, event.Timezone())
+
+	return nil
+}
+
+// Creates the flow control and parser for the incoming data and starts them running
+func (f *f1gopherlib) startProcessing(
+	requestedData parser.DataSource,
+	dataChannel <-chan connection.Payload,
+	dataFlow flowControl.FlowType,
+	assetStore connection.AssetStore,
+	session Messages.SessionType,
+	timezone *time.Location) {
+
+	config := flowControl.Config{
+		Weather:             f.weather,
+		RaceControlMessages: f.raceControlMessages,
+		Timing:              f.timing,
+		Event:               f.event,
+		Telemetry:           f.telemetry,
+		Location:            f.location,
+		EventTime:           f.eventTime,
+		Radio:               f.radio,
+		Drivers:             f.drivers,
+		Coalesce:            f.options.coalesce,
+		Granularity:         f.options.granularity,
+		Backpressure:        f.options.backpressure,
+		MemoryLimit:         f.options.memoryLimit,
+		SpillDir:            f.options.spillDir,
+	}
+	if f.options.stream {
+		config.Stream = f.stream
+	}
+	if f.options.timingDeltas {
+		config.TimingDeltas = f.timingDeltas
+	}
+
+	f.replayTiming = flowControl.CreateFlowControl(f.ctx, &f.wg, dataFlow, config, f1Log)
 
 	f.dataHandler = parser.Create(
 		f.ctx,
@@ -488,14 +639,89 @@ func (f *f1gopherlib) connectReplay(
 		dataChannel,
 		f.replayTiming,
 		assetStore,
-		event.Type,
+		session,
 		f1Log,
-		event.Timezone())
+		timezone)
 
-	go f.dataHandler.Process()
+	if f.keyframes != nil {
+		f.dataHandler.SetKeyframes(f.keyframes)
+	}
+
+	if f.options.allTelemetry {
+		f.dataHandler.SelectAllTelemetry()
+	}
+
+	// The delay needs to be set before any data is processed
 	go f.replayTiming.Run()
+	if f.isLive && f.options.delay > 0 {
+		f.replayTiming.IncrementDelay(f.options.delay)
+	}
 
-	return nil
+	go f.dataHandler.Process()
+}
+
+// Batch replays are sent straight through whatever flow was asked for because there is no realtime to follow
+func (f *f1gopherlib) batch(replay interface{ SetBatch(batch bool) }, dataFlow flowControl.FlowType) flowControl.FlowType {
+	if !f.options.batch {
+		return dataFlow
+	}
+
+	replay.SetBatch(true)
+	return flowControl.StraightThrough
+}
+
+// Keyframes are only used by replays and live sessions recorded WithDvr because other live data can't be moved. Any
+// saved in file are loaded so the replay can jump straight to them.
+func (f *f1gopherlib) createKeyframes(requestedData parser.DataSource, file string) {
+	if f.options.keyframeInterval <= 0 {
+		return
+	}
+
+	f.keyframeFile = file
+
+	if len(file) > 0 {
+		keyframes, err := parser.LoadKeyframes(file, requestedData)
+		if err == nil {
+			f.keyframes = keyframes
+			return
+		}
+
+		if !os.IsNotExist(err) {
+			f1Log.Warnf("Ignoring saved keyframes '%s': %v", file, err)
+		}
+	}
+
+	f.keyframes = parser.CreateKeyframes(requestedData, f.options.keyframeInterval)
+}
+
+func archiveHeader(event RaceEvent) connection.ArchiveHeader {
+	return connection.ArchiveHeader{
+		Country:           event.Country,
+		Name:              event.Name,
+		Session:           event.Type,
+		RaceTime:          event.RaceTime,
+		EventTime:         event.EventTime,
+		Timezone:          event.timezone,
+		Track:             event.TrackName,
+		TrackYear:         event.TrackYearCreated,
+		TimeLostInPitlane: event.TimeLostInPitlane,
+		Url:               event.Url(),
+	}
+}
+
+func eventFromArchive(header connection.ArchiveHeader) RaceEvent {
+	return RaceEvent{
+		Country:           header.Country,
+		RaceTime:          header.RaceTime,
+		EventTime:         header.EventTime,
+		Type:              header.Session,
+		Name:              header.Name,
+		timezone:          header.Timezone,
+		TrackName:         header.Track,
+		TrackYearCreated:  header.TrackYear,
+		TimeLostInPitlane: header.TimeLostInPitlane,
+		urlName:           header.Url,
+	}
 }
 
 func (f *f1gopherlib) cachePath(cache string, event RaceEvent) string {
@@ -566,6 +792,32 @@ func (f *f1gopherlib) Drivers() <-chan Messages.Drivers {
 	return f.drivers
 }
 
+// ConnectionStatus - Changes to the state of the connection to a live session. Replays don't report anything.
+func (f *f1gopherlib) ConnectionStatus() <-chan Messages.ConnectionStatus {
+	return f.connectionStatus
+}
+
+// Snapshot - The latest of everything sent so far as of the session time, it is a copy so is safe to keep
+func (f *f1gopherlib) Snapshot() Messages.Snapshot {
+	return f.replayTiming.Snapshot()
+}
+
+// Dropped - How many messages have been lost from each stream because they weren't read fast enough
+func (f *f1gopherlib) Dropped() map[Messages.StreamType]uint64 {
+	return f.replayTiming.Dropped()
+}
+
+// TimingDeltas - What has changed in a driver's timing since the last delta for them, only sent to when the session
+// was created WithTimingDeltas. Apply them in order to rebuild the timing.
+func (f *f1gopherlib) TimingDeltas() <-chan Messages.TimingDelta {
+	return f.timingDeltas
+}
+
+// Stream - Every message in the order it was processed, only sent to when the session was created WithStream
+func (f *f1gopherlib) Stream() <-chan Messages.Envelope {
+	return f.stream
+}
+
 func (f *f1gopherlib) SelectTelemetrySources(drivers []int) {
 	f.dataHandler.SelectTelemetrySources(drivers)
 }
@@ -597,6 +849,152 @@ func (f *f1gopherlib) IsPaused() bool {
 	return f.replayTiming.IsPaused()
 }
 
+// SetSpeed - Play a replay faster or slower than realtime, 2 is twice as fast and 0.5 is half speed. Live sessions
+// can't go faster than the data arrives so are unaffected.
+func (f *f1gopherlib) SetSpeed(speed float64) {
+	if f.isLive {
+		return
+	}
+
+	if speed <= 0 {
+		f1Log.Warnf("Ignoring invalid replay speed: %v", speed)
+		return
+	}
+
+	f.speedLock.Lock()
+	f.speed = speed
+	f.speedLock.Unlock()
+
+	f.connection.SetSpeed(speed)
+	f.replayTiming.SetSpeed(speed)
+}
+
+func (f *f1gopherlib) Speed() float64 {
+	f.speedLock.Lock()
+	defer f.speedLock.Unlock()
+
+	return f.speed
+}
+
+// IncrementDelay - Hold a live session back by longer so it doesn't get ahead of a delayed broadcast. Replays are
+// unaffected.
+func (f *f1gopherlib) IncrementDelay(delay time.Duration) {
+	if !f.isLive || delay <= 0 {
+		return
+	}
+
+	f.replayTiming.IncrementDelay(delay)
+}
+
+// DecrementDelay - Reduce how long a live session is held back by, it can't go below zero
+func (f *f1gopherlib) DecrementDelay(delay time.Duration) {
+	if !f.isLive || delay <= 0 {
+		return
+	}
+
+	f.replayTiming.DecrementDelay(delay)
+}
+
+// Delay - How long a live session is being held back by
+func (f *f1gopherlib) Delay() time.Duration {
+	return f.replayTiming.Delay()
+}
+
+// SeekTo - Move a replay to any time in the session, including backwards. Live sessions can only move to a time
+// already received and only when created WithDvr, otherwise they return an error. It returns once the data has moved,
+// even while paused, so don't call it from the only goroutine reading the data if the reading can fall behind.
+func (f *f1gopherlib) SeekTo(target time.Time) error {
+	return f.seekTo(target)
+}
+
+// SeekToLap - Move a replay to the start of the given lap, returning once the data has moved like SeekTo
+func (f *f1gopherlib) SeekToLap(lap int) error {
+	target, err := f.connection.LapStartTime(lap)
+	if err != nil {
+		return err
+	}
+
+	return f.seekTo(target)
+}
+
+// SeekToRemaining - Move a replay to when the session clock showed the given time remaining, returning once the data
+// has moved like SeekTo. Live sessions created WithDvr can only move to a time already received.
+func (f *f1gopherlib) SeekToRemaining(remaining time.Duration) error {
+	target, err := f.connection.TimeWhenRemaining(remaining)
+	if err != nil {
+		return err
+	}
+
+	return f.seekTo(target)
+}
+
+// CatchUpToLive - Carry on from the latest data received after pausing or rewinding a live session created WithDvr.
+// It is still held back by the Delay.
+func (f *f1gopherlib) CatchUpToLive() error {
+	buffer, exists := f.connection.(liveBuffer)
+	if !exists {
+		return errors.New("only live sessions created WithDvr can catch up to live")
+	}
+
+	if f.IsPaused() {
+		f.TogglePause()
+	}
+
+	return f.seekTo(buffer.Latest().Add(-f.Delay()))
+}
+
+// BehindLive - How far a live session created WithDvr is behind the latest data received, including the Delay. It is
+// zero for everything else.
+func (f *f1gopherlib) BehindLive() time.Duration {
+	buffer, exists := f.connection.(liveBuffer)
+	if !exists {
+		return 0
+	}
+
+	latest := buffer.Latest()
+	current := f.Snapshot().Timestamp
+	if latest.IsZero() || current.IsZero() || current.After(latest) {
+		return 0
+	}
+
+	return latest.Sub(current)
+}
+
+// Starts from the nearest keyframe if there is one so only the data after it needs processing again. The connection
+// sends the seek to the parser which passes it on to the flow control, so this waits until the flow control has it.
+func (f *f1gopherlib) seekTo(target time.Time) error {
+	f.seekLock.Lock()
+	defer f.seekLock.Unlock()
+
+	var from time.Time
+	if f.keyframes != nil {
+		from, _ = f.keyframes.Before(target)
+	}
+
+	applied := f.replayTiming.NextSeek()
+	err := f.connection.SeekTo(target, from)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-applied:
+		return nil
+
+	case <-f.dataHandler.Done():
+		// The data finished before the seek got to the parser
+		select {
+		case <-applied:
+			return nil
+		default:
+			return errors.New("the session finished before it could be moved")
+		}
+
+	case <-f.ctx.Done():
+		return f.ctx.Err()
+	}
+}
+
 func (f *f1gopherlib) Close() {
 	f.name = ""
 	f.track = ""
@@ -604,6 +1002,12 @@ func (f *f1gopherlib) Close() {
 	f.ctxShutdown()
 	f.wg.Wait()
 
+	if f.keyframes != nil && len(f.keyframeFile) > 0 {
+		if err := f.keyframes.Save(f.keyframeFile); err != nil {
+			f1Log.Errorf("Saving keyframes to '%s': %v", f.keyframeFile, err)
+		}
+	}
+
 	f.connection = nil
 	f.dataHandler = nil
 
@@ -616,4 +1020,7 @@ func (f *f1gopherlib) Close() {
 	close(f.eventTime)
 	close(f.radio)
 	close(f.drivers)
+	close(f.connectionStatus)
+	close(f.stream)
+	close(f.timingDeltas)
 }
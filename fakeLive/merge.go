@@ -0,0 +1,54 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package fakeLive
+
+import (
+	"strconv"
+)
+
+// Applies an update to the current state of a topic the same way the live timing server does. Objects are merged
+// and an object updating a list changes the entries at the indexes given by its keys.
+func merge(current any, update any) any {
+	changes, isObject := update.(map[string]any)
+	if !isObject {
+		return update
+	}
+
+	switch value := current.(type) {
+	case map[string]any:
+		for key, change := range changes {
+			value[key] = merge(value[key], change)
+		}
+		return value
+
+	case []any:
+		for key, change := range changes {
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 {
+				continue
+			}
+
+			for len(value) <= index {
+				value = append(value, nil)
+			}
+			value[index] = merge(value[index], change)
+		}
+		return value
+
+	default:
+		return changes
+	}
+}
@@ -0,0 +1,362 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package fakeLive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// Server - Plays back recorded live data as the classic SignalR 'streaming' hub used by the live timing server
+// so live sessions can be tested without a race happening. The data is paced by the original timestamps,
+// optionally sped up, and starts playing when the first client subscribes. Everyone connected sees the same point
+// in the session and anyone joining late gets the current state as the catchup.
+type Server struct {
+	log   *f1log.F1GopherLibLog
+	data  []entry
+	speed float64
+
+	upgrader websocket.Upgrader
+
+	started     time.Time
+	connections map[*client]bool
+	lock        sync.Mutex
+
+	messageId int64
+}
+
+type entry struct {
+	offset  time.Duration
+	payload connection.Payload
+}
+
+// A connected websocket, writes have to be one at a time
+type client struct {
+	conn   *websocket.Conn
+	lock   sync.Mutex
+	topics map[string]bool
+}
+
+func (c *client) send(msg any) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.conn.WriteJSON(msg)
+}
+
+type hubMessage struct {
+	Hub          string            `json:"H"`
+	Method       string            `json:"M"`
+	Args         []json.RawMessage `json:"A"`
+	InvocationId int               `json:"I"`
+}
+
+// Create - Play back the data, which should be in timestamp order, at speed times faster than it was recorded
+func Create(data []connection.Payload, speed float64, log *f1log.F1GopherLibLog) *Server {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	result := &Server{
+		log:         log,
+		speed:       speed,
+		connections: make(map[*client]bool),
+	}
+
+	// Catchups don't have a timestamp so they happen at the same time as whatever came before them
+	var start time.Time
+	var offset time.Duration
+	for _, payload := range data {
+		if len(payload.Timestamp) > 0 {
+			timestamp, err := time.Parse(time.RFC3339Nano, payload.Timestamp)
+			if err != nil {
+				log.Errorf("Fake live ignoring '%s' with invalid timestamp '%s': %v", payload.Name, payload.Timestamp, err)
+				continue
+			}
+
+			if start.IsZero() {
+				start = timestamp
+			}
+			if timestamp.Sub(start) > offset {
+				offset = timestamp.Sub(start)
+			}
+		}
+
+		result.data = append(result.data, entry{offset: offset, payload: payload})
+	}
+
+	return result
+}
+
+// CreateFromArchive - Play back an archive recorded from a live session
+func CreateFromArchive(path string, speed float64, log *f1log.F1GopherLibLog) (*Server, error) {
+	_, data, err := connection.ReadArchive(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Create(data, speed, log), nil
+}
+
+// CreateFromDir - Play back a session stored as '<Topic>.jsonStream' files, either cached from the live timing
+// server or recorded from a live session
+func CreateFromDir(dir string, speed float64, log *f1log.F1GopherLibLog) (*Server, error) {
+	data, err := connection.ReadSession(os.DirFS(dir), log)
+	if err != nil {
+		return nil, err
+	}
+
+	return Create(data, speed, log), nil
+}
+
+// Duration - How long the data takes to play back
+func (s *Server) Duration() time.Duration {
+	if len(s.data) == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(s.data[len(s.data)-1].offset) / s.speed)
+}
+
+// DropConnections - Close every connection as if the network had failed so clients have to reconnect
+func (s *Server) DropConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for c := range s.connections {
+		c.lock.Lock()
+		c.conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "connection dropped"),
+			time.Now().Add(time.Second))
+		c.lock.Unlock()
+		c.conn.Close()
+	}
+	s.connections = make(map[*client]bool)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/negotiate"):
+		s.negotiate(w)
+
+	case strings.HasSuffix(r.URL.Path, "/connect"):
+		s.connect(w, r)
+
+	case strings.HasSuffix(r.URL.Path, "/start"):
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Response":"started"}`))
+
+	case strings.HasSuffix(r.URL.Path, "/abort"):
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		// Reconnects aren't supported so clients have to connect and subscribe again
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) negotiate(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"Url":                     "/signalr",
+		"ConnectionToken":         fmt.Sprintf("token-%d", time.Now().UnixNano()),
+		"ConnectionId":            fmt.Sprintf("id-%d", time.Now().UnixNano()),
+		"KeepAliveTimeout":        20.0,
+		"DisconnectTimeout":       30.0,
+		"ConnectionTimeout":       110.0,
+		"TryWebSockets":           true,
+		"ProtocolVersion":         "1.5",
+		"TransportConnectTimeout": 5.0,
+		"LongPollDelay":           0.0,
+	})
+}
+
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.log.Errorf("Fake live websocket upgrade failed: %v", err)
+		return
+	}
+
+	c := &client{conn: conn}
+
+	s.lock.Lock()
+	s.connections[c] = true
+	s.lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		conn.Close()
+
+		s.lock.Lock()
+		delete(s.connections, c)
+		s.lock.Unlock()
+	}()
+
+	// The init message that tells the client the transport is ready
+	err = c.send(map[string]any{"C": s.nextMessageId(), "S": 1, "M": []any{}})
+	if err != nil {
+		return
+	}
+
+	subscribed := false
+	for {
+		var msg hubMessage
+		err = conn.ReadJSON(&msg)
+		if err != nil {
+			return
+		}
+
+		if !strings.EqualFold(msg.Method, "Subscribe") || subscribed {
+			c.send(map[string]any{"I": fmt.Sprint(msg.InvocationId)})
+			continue
+		}
+
+		var topics []string
+		if len(msg.Args) > 0 {
+			json.Unmarshal(msg.Args[0], &topics)
+		}
+		c.topics = make(map[string]bool)
+		for _, topic := range topics {
+			c.topics[topic] = true
+		}
+
+		subscribed = true
+		go s.play(ctx, c, msg.InvocationId)
+	}
+}
+
+func (s *Server) nextMessageId() string {
+	return fmt.Sprintf("d-%d", atomic.AddInt64(&s.messageId, 1))
+}
+
+// How far through the data we are, starting the playback if nobody has subscribed yet
+func (s *Server) position() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.started.IsZero() {
+		s.started = time.Now()
+	}
+
+	return time.Duration(float64(time.Since(s.started)) * s.speed)
+}
+
+// Sends the current state as the subscribe result and then the data as it happens
+func (s *Server) play(ctx context.Context, c *client, invocationId int) {
+	position := s.position()
+
+	state, next := s.stateAt(position, c.topics)
+	err := c.send(map[string]any{"R": state, "I": fmt.Sprint(invocationId)})
+	if err != nil {
+		return
+	}
+
+	for ; next < len(s.data); next++ {
+		current := s.data[next]
+		if current.payload.Name == connection.CatchupFile || !c.topics[current.payload.Name] {
+			continue
+		}
+
+		if current.offset > position {
+			wait := time.Duration(float64(current.offset-position) / s.speed)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			position = s.position()
+		}
+
+		err = c.send(map[string]any{
+			"C": s.nextMessageId(),
+			"M": []any{map[string]any{
+				"H": "Streaming",
+				"M": "feed",
+				"A": []any{current.payload.Name, feedData(current.payload), current.payload.Timestamp},
+			}},
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// The current state of each topic at the given point in the data and the index of the first entry after it
+func (s *Server) stateAt(position time.Duration, topics map[string]bool) (map[string]any, int) {
+	state := make(map[string]any)
+
+	next := 0
+	for ; next < len(s.data) && s.data[next].offset <= position; next++ {
+		payload := s.data[next].payload
+
+		if payload.Name == connection.CatchupFile {
+			var catchup map[string]json.RawMessage
+			if err := json.Unmarshal(payload.Data, &catchup); err != nil {
+				s.log.Errorf("Fake live ignoring invalid catchup: %v", err)
+				continue
+			}
+
+			for name, data := range catchup {
+				var value any
+				if json.Unmarshal(data, &value) == nil && topics[name] {
+					state[name] = value
+				}
+			}
+			continue
+		}
+
+		if !topics[payload.Name] {
+			continue
+		}
+
+		// Compressed data is always the complete state
+		if strings.HasSuffix(payload.Name, ".z") {
+			state[payload.Name] = string(payload.Data)
+			continue
+		}
+
+		var value any
+		if err := json.Unmarshal(payload.Data, &value); err != nil {
+			s.log.Errorf("Fake live ignoring invalid '%s' data: %v", payload.Name, err)
+			continue
+		}
+		state[payload.Name] = merge(state[payload.Name], value)
+	}
+
+	return state, next
+}
+
+func feedData(payload connection.Payload) any {
+	if strings.HasSuffix(payload.Name, ".z") {
+		return string(payload.Data)
+	}
+
+	return json.RawMessage(payload.Data)
+}
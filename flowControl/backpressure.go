@@ -0,0 +1,202 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package flowControl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// Policy - What realtime flow control does when a channel is full because it isn't being read fast enough
+type Policy int
+
+const (
+	// DropNewest - The message that doesn't fit is lost
+	DropNewest Policy = iota
+	// DropOldest - The oldest message in the channel is removed to make room
+	DropOldest
+	// Block - Wait until there is room, everything else waits too but the playback controls still work
+	Block
+	// CoalesceLatest - Hold on to the latest message for each driver until there is room, older ones are replaced.
+	// Messages that aren't for a driver only keep the latest.
+	CoalesceLatest
+)
+
+func (p Policy) String() string {
+	return [...]string{"Drop Newest", "Drop Oldest", "Block", "Coalesce Latest"}[p]
+}
+
+// Backpressure - The policy for each stream, anything not given uses DropNewest
+type Backpressure map[Messages.StreamType]Policy
+
+// How many messages were lost for each stream
+type drops struct {
+	counts map[Messages.StreamType]uint64
+	lock   sync.Mutex
+	log    *f1log.F1GopherLibLog
+}
+
+func createDrops(log *f1log.F1GopherLibLog) *drops {
+	return &drops{
+		counts: make(map[Messages.StreamType]uint64),
+		log:    log,
+	}
+}
+
+func (d *drops) add(streamType Messages.StreamType) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.counts[streamType]++
+	if d.counts[streamType] == 1 {
+		d.log.Warnf("%s messages are being dropped because they aren't being read fast enough", streamType)
+	}
+}
+
+func (d *drops) copy() map[Messages.StreamType]uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	result := make(map[Messages.StreamType]uint64, len(d.counts))
+	for streamType, count := range d.counts {
+		result[streamType] = count
+	}
+	return result
+}
+
+// A channel that is sent to following its policy
+type output[T any] struct {
+	channel    chan T
+	streamType Messages.StreamType
+	policy     Policy
+	drops      *drops
+	// Run while blocked so the playback can still be controlled, nil when there are no commands
+	commands <-chan command
+
+	// Which driver a message is for when coalescing
+	key     func(msg T) any
+	pending []T
+}
+
+func createOutput[T any](
+	channel chan T,
+	streamType Messages.StreamType,
+	policies Backpressure,
+	drops *drops,
+	commands <-chan command,
+	key func(msg T) any) *output[T] {
+
+	return &output[T]{
+		channel:    channel,
+		streamType: streamType,
+		policy:     policies[streamType],
+		drops:      drops,
+		commands:   commands,
+		key:        key,
+	}
+}
+
+// Not for a driver so only the latest is kept when coalescing
+func noDriver[T any](msg T) any {
+	return nil
+}
+
+// Returns false if msg was dropped, an older message being dropped or replaced to make room still returns true
+func (o *output[T]) send(ctx context.Context, msg T) bool {
+	switch o.policy {
+	case DropNewest:
+		select {
+		case o.channel <- msg:
+		default:
+			// Data loss
+			o.drops.add(o.streamType)
+			return false
+		}
+
+	case DropOldest:
+		select {
+		case o.channel <- msg:
+			return true
+		default:
+		}
+
+		select {
+		case <-o.channel:
+			o.drops.add(o.streamType)
+		default:
+			// Emptied since trying to send
+		}
+
+		select {
+		case o.channel <- msg:
+		default:
+			// Data loss
+			o.drops.add(o.streamType)
+			return false
+		}
+
+	case Block:
+		for {
+			select {
+			case o.channel <- msg:
+				return true
+			case cmd := <-o.commands:
+				cmd.run()
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+	case CoalesceLatest:
+		o.flush()
+
+		for x := range o.pending {
+			if o.key(o.pending[x]) == o.key(msg) {
+				o.pending[x] = msg
+				o.drops.add(o.streamType)
+				return true
+			}
+		}
+
+		if len(o.pending) > 0 {
+			o.pending = append(o.pending, msg)
+			return true
+		}
+
+		select {
+		case o.channel <- msg:
+		default:
+			o.pending = append(o.pending, msg)
+		}
+	}
+
+	return true
+}
+
+// Sends whatever is being held back for coalescing while there is room
+func (o *output[T]) flush() {
+	for len(o.pending) > 0 {
+		select {
+		case o.channel <- o.pending[0]:
+			o.pending = o.pending[1:]
+		default:
+			return
+		}
+	}
+}
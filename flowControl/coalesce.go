@@ -0,0 +1,48 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package flowControl
+
+import (
+	"time"
+)
+
+// Removes the messages due by currentTime that have a later due message for the same key so only the latest is sent
+// this tick. The messages that are kept stay in order.
+func coalesceDue[T any](msgs []T, currentTime time.Time, timestamp func(msg T) time.Time, key func(msg T) any) []T {
+	due := 0
+	for due < len(msgs) && !timestamp(msgs[due]).After(currentTime) {
+		due++
+	}
+	if due < 2 {
+		return msgs
+	}
+
+	latest := make(map[any]int, due)
+	for x := 0; x < due; x++ {
+		latest[key(msgs[x])] = x
+	}
+
+	kept := 0
+	for x := 0; x < due; x++ {
+		if latest[key(msgs[x])] == x {
+			msgs[kept] = msgs[x]
+			kept++
+		}
+	}
+
+	kept += copy(msgs[kept:], msgs[due:])
+	return msgs[:kept]
+}
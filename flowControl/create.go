@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
 )
 
 type Flow interface {
@@ -40,6 +41,16 @@ type Flow interface {
 	SkipToSessionStart(start time.Time)
 	TogglePause()
 	IsPaused() bool
+	SetSpeed(speed float64)
+	SeekTo(target time.Time)
+	NextSeek() <-chan struct{}
+	SendToSubscriptions(output chan Messages.Envelope)
+	Snapshot() Messages.Snapshot
+	Dropped() map[Messages.StreamType]uint64
+
+	IncrementDelay(delay time.Duration)
+	DecrementDelay(delay time.Duration)
+	Delay() time.Duration
 }
 
 type FlowType int
@@ -49,47 +60,107 @@ const (
 	StraightThrough
 )
 
+// Config - Where the flow control sends the data and how. Anything optional is left as its zero value when it isn't
+// wanted.
+type Config struct {
+	Weather             chan Messages.Weather
+	RaceControlMessages chan Messages.RaceControlMessage
+	Timing              chan Messages.Timing
+	Event               chan Messages.Event
+	Telemetry           chan Messages.Telemetry
+	Location            chan Messages.Location
+	EventTime           chan<- Messages.EventTime
+	Radio               chan Messages.Radio
+	Drivers             chan Messages.Drivers
+
+	// Optional, also send what changed in the timing and every message in order
+	TimingDeltas chan Messages.TimingDelta
+	Stream       chan Messages.Envelope
+
+	// Only used by realtime flow control, see the options with the same names
+	Coalesce     bool
+	Granularity  time.Duration
+	Backpressure Backpressure
+	MemoryLimit  int
+	SpillDir     string
+}
+
 func CreateFlowControl(
 	ctx context.Context,
 	wg *sync.WaitGroup,
 	flowType FlowType,
-	outputWeather chan<- Messages.Weather,
-	outputRaceControlMessages chan<- Messages.RaceControlMessage,
-	outputTimingMessages chan<- Messages.Timing,
-	outputEvent chan<- Messages.Event,
-	outputTelemetry chan<- Messages.Telemetry,
-	outputLocation chan<- Messages.Location,
-	outputEventTime chan<- Messages.EventTime,
-	outputRadio chan<- Messages.Radio,
-	outputDrivers chan<- Messages.Drivers) Flow {
+	config Config,
+	log *f1log.F1GopherLibLog) Flow {
 
 	switch flowType {
 	case Realtime:
+		policies := config.Backpressure
+		drops := createDrops(log)
+		budget := &memoryBudget{limit: config.MemoryLimit}
+		commands := make(chan command)
+
+		var stream *output[Messages.Envelope]
+		if config.Stream != nil {
+			stream = createOutput(config.Stream, Messages.EnvelopeStream, policies, drops, commands,
+				noDriver[Messages.Envelope])
+		}
+
 		return &realtime{
-			ctx:                       ctx,
-			wg:                        wg,
-			outputWeather:             outputWeather,
-			outputRaceControlMessages: outputRaceControlMessages,
-			outputTimingMessages:      outputTimingMessages,
-			outputEvent:               outputEvent,
-			outputTelemetry:           outputTelemetry,
-			outputLocation:            outputLocation,
-			outputEventTime:           outputEventTime,
-			outputRadio:               outputRadio,
-			outputDrivers:             outputDrivers,
+			speed:    1,
+			ctx:      ctx,
+			wg:       wg,
+			commands: commands,
+			outputWeather: createOutput(config.Weather, Messages.WeatherStream, policies, drops, commands,
+				noDriver[Messages.Weather]),
+			outputRaceControlMessages: createOutput(config.RaceControlMessages, Messages.RaceControlStream, policies,
+				drops, commands, noDriver[Messages.RaceControlMessage]),
+			outputTimingMessages: createOutput(config.Timing, Messages.TimingStream, policies, drops, commands,
+				func(msg Messages.Timing) any { return msg.Number }),
+			outputEvent: createOutput(config.Event, Messages.EventStream, policies, drops, commands,
+				noDriver[Messages.Event]),
+			outputTelemetry: createOutput(config.Telemetry, Messages.TelemetryStream, policies, drops, commands,
+				func(msg Messages.Telemetry) any { return msg.DriverNumber }),
+			outputLocation: createOutput(config.Location, Messages.LocationStream, policies, drops, commands,
+				func(msg Messages.Location) any { return msg.DriverNumber }),
+			outputEventTime: config.EventTime,
+			outputRadio: createOutput(config.Radio, Messages.RadioStream, policies, drops, commands,
+				func(msg Messages.Radio) any { return msg.Driver }),
+			outputDrivers: createOutput(config.Drivers, Messages.DriversStream, policies, drops, commands,
+				noDriver[Messages.Drivers]),
+			timing:       createSpillBuffer[Messages.Timing](budget, config.SpillDir, log),
+			telemetry:    createSpillBuffer[Messages.Telemetry](budget, config.SpillDir, log),
+			location:     createSpillBuffer[Messages.Location](budget, config.SpillDir, log),
+			stream:       createSpillBuffer[Messages.Envelope](budget, config.SpillDir, log),
+			drops:        drops,
+			timingDeltas: createTimingDeltas(config.TimingDeltas, policies, drops, commands, log),
+			coalesce:     config.Coalesce,
+			granularity:  config.Granularity,
+			outputStream: stream,
+			policies:     policies,
 		}
 
 	case StraightThrough:
+		drops := createDrops(log)
+
 		return &straightThrough{
-			outputWeather:             outputWeather,
-			outputRaceControlMessages: outputRaceControlMessages,
-			outputTimingMessages:      outputTimingMessages,
-			outputEvent:               outputEvent,
-			outputTelemetry:           outputTelemetry,
-			outputLocation:            outputLocation,
-			outputEventTime:           outputEventTime,
-			outputRadio:               outputRadio,
-			outputDrivers:             outputDrivers,
+			outputWeather:             config.Weather,
+			outputRaceControlMessages: config.RaceControlMessages,
+			outputTimingMessages:      config.Timing,
+			outputEvent:               config.Event,
+			outputTelemetry:           config.Telemetry,
+			outputLocation:            config.Location,
+			outputEventTime:           config.EventTime,
+			outputRadio:               config.Radio,
+			outputDrivers:             config.Drivers,
+			// Everything else waits to be read so the deltas do too
+			timingDeltas: createTimingDeltas(config.TimingDeltas, Backpressure{Messages.TimingDeltaStream: Block},
+				drops, nil, log),
+			outputStream: config.Stream,
+			policies:     config.Backpressure,
+			drops:        drops,
+			wake:         make(chan struct{}, 1),
+			ctx:          ctx,
+			wg:           wg,
 		}
 
 	default:
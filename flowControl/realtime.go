@@ -23,43 +23,80 @@ import (
 	"github.com/stephenhoran/f1gopherlib/Messages"
 )
 
+// How much the time moves on each tick
+const realtimeTick = 500 * time.Millisecond
+
 type realtime struct {
-	outputWeather             chan<- Messages.Weather
-	outputRaceControlMessages chan<- Messages.RaceControlMessage
-	outputTimingMessages      chan<- Messages.Timing
-	outputEvent               chan<- Messages.Event
-	outputTelemetry           chan<- Messages.Telemetry
-	outputLocation            chan<- Messages.Location
+	outputWeather             *output[Messages.Weather]
+	outputRaceControlMessages *output[Messages.RaceControlMessage]
+	outputTimingMessages      *output[Messages.Timing]
+	outputEvent               *output[Messages.Event]
+	outputTelemetry           *output[Messages.Telemetry]
+	outputLocation            *output[Messages.Location]
 	outputEventTime           chan<- Messages.EventTime
-	outputRadio               chan<- Messages.Radio
-	outputDrivers             chan<- Messages.Drivers
+	outputRadio               *output[Messages.Radio]
+	outputDrivers             *output[Messages.Drivers]
+	drops                     *drops
+	timingDeltas              *timingDeltas
+
+	// Only send the latest timing for each driver and the latest event each tick
+	coalesce bool
+
+	// When set everything is sent at its own time instead of every few ticks, this is the shortest time between sends
+	granularity time.Duration
+	nextClock   time.Time
 
 	weatherLock     sync.Mutex
 	weather         []Messages.Weather
 	raceControlLock sync.Mutex
 	raceControl     []Messages.RaceControlMessage
 	timingLock      sync.Mutex
-	timing          []Messages.Timing
+	timing          *spillBuffer[Messages.Timing]
 	eventLock       sync.Mutex
 	event           []Messages.Event
 	telemetryLock   sync.Mutex
-	telemetry       []Messages.Telemetry
+	telemetry       *spillBuffer[Messages.Telemetry]
 	locationLock    sync.Mutex
-	location        []Messages.Location
+	location        *spillBuffer[Messages.Location]
 	radioLock       sync.Mutex
 	radio           []Messages.Radio
 	driversLock     sync.Mutex
 	drivers         []Messages.Drivers
 
+	// Everything in the order it was added, only used when there is a stream or subscriptions output
+	outputStream      *output[Messages.Envelope]
+	stream            *spillBuffer[Messages.Envelope]
+	streamLock        sync.Mutex
+	streamSequence    uint64
+	subscriptions     *output[Messages.Envelope]
+	subscriptionsLock sync.Mutex
+	policies          Backpressure
+
+	state state
+
 	currentTime   time.Time
 	currentLap    int
 	currentStatus Messages.SessionState
 	remainingTime time.Duration
 	clockStopped  bool
 
+	// Only changed by commands so they are only used on the Run goroutine
 	incrementLapCount int
 	incrementTime     time.Duration
 	isPaused          bool
+	commands          chan command
+	// How far behind the data the time is kept
+	delay time.Duration
+
+	// How many times faster than realtime to send the data
+	speed     float64
+	speedLock sync.Mutex
+
+	// Where the data has been moved to. Data from before it only arrives while catching up to the new time so only
+	// the latest state is kept.
+	seekTarget time.Time
+	seekLock   sync.Mutex
+	seeks      seekSignal
 
 	skipToTime            time.Time
 	ignoreRadioMsgsBefore time.Time
@@ -73,66 +110,86 @@ type realtime struct {
 func (f *realtime) Run() {
 	f.wg.Add(1)
 	defer f.wg.Done()
-	ticker := time.NewTicker(500 * time.Millisecond)
+	// How far the time moves on each tick
+	step := realtimeTick
+	tickInterval := f.tickInterval(step)
+	ticker := time.NewTicker(tickInterval)
 	counter := 2
 
 	for {
 		select {
 		case <-f.ctx.Done():
 			ticker.Stop()
+
+			// Remove anything written to disk
+			f.timingLock.Lock()
+			f.timing.clear()
+			f.timingLock.Unlock()
+			f.telemetryLock.Lock()
+			f.telemetry.clear()
+			f.telemetryLock.Unlock()
+			f.locationLock.Lock()
+			f.location.clear()
+			f.locationLock.Unlock()
+			f.streamLock.Lock()
+			f.stream.clear()
+			f.streamLock.Unlock()
 			return
 
+		case cmd := <-f.commands:
+			cmd.run()
+
 		case <-ticker.C:
+			// Each tick always moves the time on by the same amount so a different speed changes how often they happen
+			if interval := f.tickInterval(step); interval != tickInterval {
+				tickInterval = interval
+				ticker.Reset(tickInterval)
+			}
+
 			if f.isPaused {
 				continue
 			}
 
+			f.flushOutputs()
+
 			// We want to skip any radio messages when we jump forward in time
 			if !f.skipToTime.IsZero() {
 				f.currentTime = f.skipToTime
 				f.ignoreRadioMsgsBefore = f.skipToTime
 				f.skipToTime = time.Time{}
 
-				f.radioLock.Lock()
-				for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-					f.radio = f.radio[1:]
-				}
-				f.radioLock.Unlock()
+				f.skipRadio()
 			}
 
-			if counter == 3 {
+			if counter == 3 || f.granularity > 0 {
 				counter = 0
 
 				f.eventLock.Lock()
 				if len(f.event) > 0 {
 
 					if f.currentTime.IsZero() && !f.event[0].Timestamp.IsZero() {
-						f.currentTime = f.event[0].Timestamp
+						f.currentTime = f.event[0].Timestamp.Add(-f.delay)
 						f.clockStopped = f.event[0].ClockStopped
 					}
 
 					increment := f.incrementLapCount
 
 					if increment > 0 {
-						// TODO - thread safe
 						f.incrementLapCount = f.incrementLapCount - increment
 						targetLap := f.currentLap + increment
 						var incrementTime time.Time
 
 						for len(f.event) > 0 && f.currentLap < targetLap {
-							select {
-							case f.outputEvent <- f.event[0]:
-								f.currentLap = f.event[0].CurrentLap
-								f.currentStatus = f.event[0].Status
-								incrementTime = f.event[0].Timestamp
+							f.state.setEvent(f.event[0])
 
-								f.sessionStart = f.event[0].SessionStartTime
-								f.sessionLength = f.event[0].RemainingTime
-								f.clockStopped = f.event[0].ClockStopped
+							f.outputEvent.send(f.ctx, f.event[0])
+							f.currentLap = f.event[0].CurrentLap
+							f.currentStatus = f.event[0].Status
+							incrementTime = f.event[0].Timestamp
 
-							default:
-								// Data loss
-							}
+							f.sessionStart = f.event[0].SessionStartTime
+							f.sessionLength = f.event[0].RemainingTime
+							f.clockStopped = f.event[0].ClockStopped
 
 							f.event = f.event[1:]
 						}
@@ -140,26 +197,24 @@ func (f *realtime) Run() {
 						f.currentTime = incrementTime
 
 						// We want to skip any radio messages when we jump forward in time
-						f.radioLock.Lock()
-						for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-							f.radio = f.radio[1:]
-						}
-						f.radioLock.Unlock()
+						f.skipRadio()
 
 					} else {
+						if f.coalesce {
+							f.event = coalesceDue(f.event, f.currentTime,
+								func(msg Messages.Event) time.Time { return msg.Timestamp }, noDriver[Messages.Event])
+						}
+
 						for len(f.event) > 0 && (f.event[0].Timestamp.Before(f.currentTime) || f.event[0].Timestamp.Equal(f.currentTime)) {
-							select {
-							case f.outputEvent <- f.event[0]:
-								f.currentLap = f.event[0].CurrentLap
-								f.currentStatus = f.event[0].Status
+							f.state.setEvent(f.event[0])
 
-								f.sessionStart = f.event[0].SessionStartTime
-								f.sessionLength = f.event[0].RemainingTime
-								f.clockStopped = f.event[0].ClockStopped
+							f.outputEvent.send(f.ctx, f.event[0])
+							f.currentLap = f.event[0].CurrentLap
+							f.currentStatus = f.event[0].Status
 
-							default:
-								// Data loss
-							}
+							f.sessionStart = f.event[0].SessionStartTime
+							f.sessionLength = f.event[0].RemainingTime
+							f.clockStopped = f.event[0].ClockStopped
 
 							f.event = f.event[1:]
 						}
@@ -170,11 +225,9 @@ func (f *realtime) Run() {
 				f.raceControlLock.Lock()
 				if len(f.raceControl) > 0 {
 					for len(f.raceControl) > 0 && (f.raceControl[0].Timestamp.Before(f.currentTime) || f.raceControl[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputRaceControlMessages <- f.raceControl[0]:
-						default:
-							// Data loss
-						}
+						f.state.addRaceControl(f.raceControl[0])
+
+						f.outputRaceControlMessages.send(f.ctx, f.raceControl[0])
 
 						f.raceControl = f.raceControl[1:]
 					}
@@ -184,11 +237,9 @@ func (f *realtime) Run() {
 				f.weatherLock.Lock()
 				if len(f.weather) > 0 {
 					for len(f.weather) > 0 && (f.weather[0].Timestamp.Before(f.currentTime) || f.weather[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputWeather <- f.weather[0]:
-						default:
-							// Data loss
-						}
+						f.state.setWeather(f.weather[0])
+
+						f.outputWeather.send(f.ctx, f.weather[0])
 
 						f.weather = f.weather[1:]
 					}
@@ -196,29 +247,29 @@ func (f *realtime) Run() {
 				f.weatherLock.Unlock()
 
 				f.timingLock.Lock()
-				if len(f.timing) > 0 {
-					for len(f.timing) > 0 && (f.timing[0].Timestamp.Before(f.currentTime) || f.timing[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputTimingMessages <- f.timing[0]:
-						default:
-							// Data loss
-						}
+				if f.coalesce {
+					f.timing.coalesce(f.currentTime,
+						func(msg Messages.Timing) time.Time { return msg.Timestamp },
+						func(msg Messages.Timing) any { return msg.Number })
+				}
+				if f.timing.len() > 0 {
+					for f.timing.len() > 0 && (f.timing.front().Timestamp.Before(f.currentTime) || f.timing.front().Timestamp.Equal(f.currentTime)) {
+						f.state.setTiming(f.timing.front())
+
+						f.outputTimingMessages.send(f.ctx, f.timing.front())
+						f.timingDeltas.send(f.ctx, f.timing.front())
 
-						f.timing = f.timing[1:]
+						f.timing.pop()
 					}
 				}
 				f.timingLock.Unlock()
 
 				f.telemetryLock.Lock()
-				if len(f.telemetry) > 0 {
-					for len(f.telemetry) > 0 && (f.telemetry[0].Timestamp.Before(f.currentTime) || f.telemetry[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputTelemetry <- f.telemetry[0]:
-						default:
-							// Data loss
-						}
+				if f.telemetry.len() > 0 {
+					for f.telemetry.len() > 0 && (f.telemetry.front().Timestamp.Before(f.currentTime) || f.telemetry.front().Timestamp.Equal(f.currentTime)) {
+						f.outputTelemetry.send(f.ctx, f.telemetry.front())
 
-						f.telemetry = f.telemetry[1:]
+						f.telemetry.pop()
 					}
 				}
 				f.telemetryLock.Unlock()
@@ -232,11 +283,7 @@ func (f *realtime) Run() {
 							continue
 						}
 
-						select {
-						case f.outputRadio <- f.radio[0]:
-						default:
-							// Data loss
-						}
+						f.outputRadio.send(f.ctx, f.radio[0])
 
 						f.radio = f.radio[1:]
 					}
@@ -247,11 +294,9 @@ func (f *realtime) Run() {
 				if len(f.drivers) > 0 {
 					// Send the driver list immediately so that users know who the drivers are before other data comes
 					// through.
-					select {
-					case f.outputDrivers <- f.drivers[0]:
-					default:
-						// Data loss
-					}
+					f.state.setDrivers(f.drivers[0])
+
+					f.outputDrivers.send(f.ctx, f.drivers[0])
 
 					f.drivers = f.drivers[1:]
 				}
@@ -261,15 +306,13 @@ func (f *realtime) Run() {
 			}
 
 			f.locationLock.Lock()
-			if len(f.location) > 0 {
-				for len(f.location) > 0 && (f.location[0].Timestamp.Before(f.currentTime) || f.location[0].Timestamp.Equal(f.currentTime)) {
-					select {
-					case f.outputLocation <- f.location[0]:
-					default:
-						// Data loss
-					}
+			if f.location.len() > 0 {
+				for f.location.len() > 0 && (f.location.front().Timestamp.Before(f.currentTime) || f.location.front().Timestamp.Equal(f.currentTime)) {
+					f.state.setLocation(f.location.front())
+
+					f.outputLocation.send(f.ctx, f.location.front())
 
-					f.location = f.location[1:]
+					f.location.pop()
 				}
 			}
 			f.locationLock.Unlock()
@@ -279,15 +322,10 @@ func (f *realtime) Run() {
 				if increment > 0 {
 					f.currentTime = f.currentTime.Add(increment)
 
-					// TODO - do thread safe
 					f.incrementTime = f.incrementTime - increment
 
 					// We want to skip any radio messages when we jump forward in time
-					f.radioLock.Lock()
-					for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-						f.radio = f.radio[1:]
-					}
-					f.radioLock.Unlock()
+					f.skipRadio()
 				}
 
 				if !f.sessionStart.IsZero() && !f.clockStopped {
@@ -299,9 +337,37 @@ func (f *realtime) Run() {
 					}
 				}
 
-				f.outputEventTime <- Messages.EventTime{Timestamp: f.currentTime, Remaining: f.remainingTime}
+				f.state.setTime(f.currentTime)
+				f.sendStream()
+
+				// Only send the time as often as a normal tick, after moving to a different time it is sent straight away
+				if f.granularity == 0 || !f.currentTime.Before(f.nextClock) || f.nextClock.Sub(f.currentTime) > realtimeTick {
+					f.nextClock = f.currentTime.Add(realtimeTick)
+
+					eventTime := Messages.EventTime{Timestamp: f.currentTime, Remaining: f.remainingTime}
+					if f.outputStream != nil {
+						// Time may not be read when everything comes from the stream
+						select {
+						case f.outputEventTime <- eventTime:
+						default:
+							// Data loss
+						}
+					} else {
+						f.sendTime(eventTime)
+					}
+
+					f.sendEnvelope(Messages.Envelope{Type: Messages.EventTimeStream, Timestamp: eventTime.Timestamp, Data: eventTime})
+				}
+
+				if f.granularity > 0 {
+					step = f.nextStep()
+					if interval := f.tickInterval(step); interval != tickInterval {
+						tickInterval = interval
+						ticker.Reset(tickInterval)
+					}
+				}
 
-				f.currentTime = f.currentTime.Add(time.Millisecond * 500)
+				f.currentTime = f.currentTime.Add(step)
 			}
 		}
 	}
@@ -310,75 +376,432 @@ func (f *realtime) Run() {
 func (f *realtime) AddWeather(weather Messages.Weather) {
 	f.weatherLock.Lock()
 	defer f.weatherLock.Unlock()
+
+	if f.beforeSeek(weather.Timestamp) && len(f.weather) > 0 {
+		f.weather[len(f.weather)-1] = weather
+		f.replaceInStream(Messages.WeatherStream, weather.Timestamp, weather, func(any) bool { return true })
+		return
+	}
+
 	f.weather = append(f.weather, weather)
+	f.addToStream(Messages.WeatherStream, weather.Timestamp, weather)
 }
 
 func (f *realtime) AddRaceControlMessage(raceControlMessage Messages.RaceControlMessage) {
+	if f.beforeSeek(raceControlMessage.Timestamp) {
+		return
+	}
+
 	f.raceControlLock.Lock()
 	defer f.raceControlLock.Unlock()
 	f.raceControl = append(f.raceControl, raceControlMessage)
+	f.addToStream(Messages.RaceControlStream, raceControlMessage.Timestamp, raceControlMessage)
 }
 
 func (f *realtime) AddTiming(timing Messages.Timing) {
 	f.timingLock.Lock()
 	defer f.timingLock.Unlock()
-	f.timing = append(f.timing, timing)
+
+	// Only the latest timing for each driver matters when catching up
+	if f.beforeSeek(timing.Timestamp) &&
+		f.timing.replace(timing, func(msg Messages.Timing) bool { return msg.Number == timing.Number }) {
+
+		f.replaceInStream(Messages.TimingStream, timing.Timestamp, timing, func(data any) bool {
+			return data.(Messages.Timing).Number == timing.Number
+		})
+		return
+	}
+
+	f.timing.add(timing)
+	f.addToStream(Messages.TimingStream, timing.Timestamp, timing)
 }
 
 func (f *realtime) AddEvent(event Messages.Event) {
 	f.eventLock.Lock()
 	defer f.eventLock.Unlock()
+
+	if f.beforeSeek(event.Timestamp) && len(f.event) > 0 {
+		f.event[len(f.event)-1] = event
+		f.replaceInStream(Messages.EventStream, event.Timestamp, event, func(any) bool { return true })
+		return
+	}
+
 	f.event = append(f.event, event)
+	f.addToStream(Messages.EventStream, event.Timestamp, event)
 }
 
 func (f *realtime) AddTelemetry(telemetry Messages.Telemetry) {
+	if f.beforeSeek(telemetry.Timestamp) {
+		return
+	}
+
 	f.telemetryLock.Lock()
 	defer f.telemetryLock.Unlock()
-	f.telemetry = append(f.telemetry, telemetry)
+	f.telemetry.add(telemetry)
+	f.addToStream(Messages.TelemetryStream, telemetry.Timestamp, telemetry)
 }
 
 func (f *realtime) AddLocation(location Messages.Location) {
+	if f.beforeSeek(location.Timestamp) {
+		return
+	}
+
 	f.locationLock.Lock()
 	defer f.locationLock.Unlock()
-	f.location = append(f.location, location)
+	f.location.add(location)
+	f.addToStream(Messages.LocationStream, location.Timestamp, location)
 }
 
 func (f *realtime) AddRadio(radio Messages.Radio) {
+	if f.beforeSeek(radio.Timestamp) {
+		return
+	}
+
 	f.radioLock.Lock()
 	defer f.radioLock.Unlock()
 	f.radio = append(f.radio, radio)
+	f.addToStream(Messages.RadioStream, radio.Timestamp, radio)
 }
 
 func (f *realtime) AddDrivers(drivers Messages.Drivers) {
 	f.driversLock.Lock()
 	defer f.driversLock.Unlock()
 	f.drivers = append(f.drivers, drivers)
+	f.addToStream(Messages.DriversStream, drivers.Timestamp, drivers)
+}
+
+// A change to the playback made on the Run goroutine
+type command struct {
+	apply func()
+	done  chan struct{}
+}
+
+func (c command) run() {
+	c.apply()
+	close(c.done)
+}
+
+// Runs apply on the Run goroutine between ticks so nothing it changes needs locking. It returns once apply has run or
+// straight away if the flow has stopped.
+func (f *realtime) control(apply func()) {
+	cmd := command{apply: apply, done: make(chan struct{})}
+
+	select {
+	case f.commands <- cmd:
+	case <-f.ctx.Done():
+		return
+	}
+
+	<-cmd.done
+}
+
+// Waits for the time to be read while still handling commands so the reader can pause without a deadlock
+func (f *realtime) sendTime(eventTime Messages.EventTime) {
+	for {
+		select {
+		case f.outputEventTime <- eventTime:
+			return
+		case cmd := <-f.commands:
+			cmd.run()
+		case <-f.ctx.Done():
+			return
+		}
+	}
 }
 
 func (f *realtime) IncrementLap() {
-	f.incrementLapCount++
+	f.control(func() { f.incrementLapCount++ })
 }
 
 func (f *realtime) IncrementTime(duration time.Duration) {
-	f.incrementTime += duration
+	f.control(func() { f.incrementTime += duration })
 }
 
 func (f *realtime) SkipToSessionStart(start time.Time) {
-	f.skipToTime = start
+	f.control(func() { f.skipToTime = start })
 }
 
 func (f *realtime) TogglePause() {
-	f.isPaused = !f.isPaused
+	f.control(func() { f.isPaused = !f.isPaused })
 }
 
 func (f *realtime) IsPaused() bool {
-	return f.isPaused
+	var isPaused bool
+	f.control(func() { isPaused = f.isPaused })
+	return isPaused
+}
+
+// SeekTo - Throw away everything waiting to be sent and carry on from target. It returns once the time has moved.
+func (f *realtime) SeekTo(target time.Time) {
+	f.state.seek(target)
+
+	f.weatherLock.Lock()
+	f.weather = nil
+	f.weatherLock.Unlock()
+	f.raceControlLock.Lock()
+	f.raceControl = nil
+	f.raceControlLock.Unlock()
+	f.timingLock.Lock()
+	f.timing.clear()
+	f.timingLock.Unlock()
+	f.eventLock.Lock()
+	f.event = nil
+	f.eventLock.Unlock()
+	f.telemetryLock.Lock()
+	f.telemetry.clear()
+	f.telemetryLock.Unlock()
+	f.locationLock.Lock()
+	f.location.clear()
+	f.locationLock.Unlock()
+	f.radioLock.Lock()
+	f.radio = nil
+	f.radioLock.Unlock()
+	f.streamLock.Lock()
+	f.stream.clear()
+	f.streamLock.Unlock()
+
+	f.seekLock.Lock()
+	f.seekTarget = target
+	f.seekLock.Unlock()
+
+	// Applied straight away, even while paused
+	f.control(func() {
+		f.currentTime = target
+		f.ignoreRadioMsgsBefore = target
+		f.state.setTime(target)
+	})
+	f.seeks.applied()
+}
+
+// NextSeek - Closed once the next SeekTo has been applied
+func (f *realtime) NextSeek() <-chan struct{} {
+	return f.seeks.wait()
+}
+
+// We want to skip any radio messages when we jump forward in time
+func (f *realtime) skipRadio() {
+	f.radioLock.Lock()
+	for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
+		f.radio = f.radio[1:]
+	}
+	f.radioLock.Unlock()
+
+	f.streamLock.Lock()
+	defer f.streamLock.Unlock()
+
+	// The ones on disk are checked later so it can't use the current time then
+	skippedTo := f.currentTime
+	f.stream.filter(func(envelope Messages.Envelope) bool {
+		return envelope.Type != Messages.RadioStream || envelope.Timestamp.After(skippedTo)
+	})
+}
+
+func (f *realtime) addToStream(streamType Messages.StreamType, timestamp time.Time, data any) {
+	if f.outputStream == nil && f.currentSubscriptions() == nil {
+		return
+	}
+
+	f.streamLock.Lock()
+	defer f.streamLock.Unlock()
+	f.stream.add(Messages.Envelope{Type: streamType, Timestamp: timestamp, Data: data})
+}
+
+// Swaps the latest message of the type that matches for a newer one so only the latest state is sent. When it can't
+// be swapped, because it has been written to disk, the newer one is added instead.
+func (f *realtime) replaceInStream(streamType Messages.StreamType, timestamp time.Time, data any, matches func(data any) bool) {
+	if f.outputStream == nil && f.currentSubscriptions() == nil {
+		return
+	}
+
+	f.streamLock.Lock()
+	defer f.streamLock.Unlock()
+
+	envelope := Messages.Envelope{Type: streamType, Timestamp: timestamp, Data: data}
+	if !f.stream.replace(envelope, func(msg Messages.Envelope) bool { return msg.Type == streamType && matches(msg.Data) }) {
+		f.stream.add(envelope)
+	}
+}
+
+// Sends everything that is due in the order it was added. It stops at the first message that isn't due to keep the
+// order so the driver list is always due to match it being sent straight away on its own channel.
+func (f *realtime) sendStream() {
+	f.streamLock.Lock()
+	defer f.streamLock.Unlock()
+
+	for f.stream.len() > 0 {
+		envelope := f.stream.front()
+		if envelope.Type != Messages.DriversStream && envelope.Timestamp.After(f.currentTime) {
+			break
+		}
+
+		// We want to skip any radio messages before we jumped to the start of the session
+		if envelope.Type != Messages.RadioStream ||
+			f.ignoreRadioMsgsBefore.IsZero() ||
+			!envelope.Timestamp.Before(f.ignoreRadioMsgsBefore) {
+
+			f.sendEnvelope(envelope)
+		}
+
+		f.stream.pop()
+	}
+}
+
+// Only called from Run so the sequence doesn't need locking
+func (f *realtime) sendEnvelope(envelope Messages.Envelope) {
+	subscriptions := f.currentSubscriptions()
+	if f.outputStream == nil && subscriptions == nil {
+		return
+	}
+
+	f.streamSequence++
+	envelope.Sequence = f.streamSequence
+
+	if f.outputStream != nil {
+		f.outputStream.send(f.ctx, envelope)
+	}
+	if subscriptions != nil {
+		subscriptions.send(f.ctx, envelope)
+	}
 }
 
-func (f *realtime) IncrementDelay(delay time.Duration) {}
+func (f *realtime) currentSubscriptions() *output[Messages.Envelope] {
+	f.subscriptionsLock.Lock()
+	defer f.subscriptionsLock.Unlock()
 
-func (f *realtime) DecrementDelay(delay time.Duration) {}
+	return f.subscriptions
+}
+
+// SendToSubscriptions - Also send everything from now on to output, following the SubscriptionStream policy
+func (f *realtime) SendToSubscriptions(output chan Messages.Envelope) {
+	f.subscriptionsLock.Lock()
+	defer f.subscriptionsLock.Unlock()
+
+	f.subscriptions = createOutput(output, Messages.SubscriptionStream, f.policies, f.drops, f.commands,
+		noDriver[Messages.Envelope])
+}
+
+func (f *realtime) beforeSeek(timestamp time.Time) bool {
+	f.seekLock.Lock()
+	defer f.seekLock.Unlock()
+	return timestamp.Before(f.seekTarget)
+}
+
+func (f *realtime) SetSpeed(speed float64) {
+	f.speedLock.Lock()
+	defer f.speedLock.Unlock()
+
+	f.speed = speed
+}
+
+func (f *realtime) tickInterval(step time.Duration) time.Duration {
+	f.speedLock.Lock()
+	defer f.speedLock.Unlock()
+
+	return time.Duration(float64(step) / f.speed)
+}
+
+// How far to move the time so the next message waiting is sent when it is due. It is at least the granularity and at
+// most a normal tick so the time keeps being sent.
+func (f *realtime) nextStep() time.Duration {
+	next := f.nextClock
+	earliest := func(timestamp time.Time) {
+		if timestamp.After(f.currentTime) && timestamp.Before(next) {
+			next = timestamp
+		}
+	}
+
+	f.eventLock.Lock()
+	if len(f.event) > 0 {
+		earliest(f.event[0].Timestamp)
+	}
+	f.eventLock.Unlock()
+	f.raceControlLock.Lock()
+	if len(f.raceControl) > 0 {
+		earliest(f.raceControl[0].Timestamp)
+	}
+	f.raceControlLock.Unlock()
+	f.weatherLock.Lock()
+	if len(f.weather) > 0 {
+		earliest(f.weather[0].Timestamp)
+	}
+	f.weatherLock.Unlock()
+	f.timingLock.Lock()
+	if f.timing.len() > 0 {
+		earliest(f.timing.front().Timestamp)
+	}
+	f.timingLock.Unlock()
+	f.telemetryLock.Lock()
+	if f.telemetry.len() > 0 {
+		earliest(f.telemetry.front().Timestamp)
+	}
+	f.telemetryLock.Unlock()
+	f.radioLock.Lock()
+	if len(f.radio) > 0 {
+		earliest(f.radio[0].Timestamp)
+	}
+	f.radioLock.Unlock()
+	f.locationLock.Lock()
+	if f.location.len() > 0 {
+		earliest(f.location.front().Timestamp)
+	}
+	f.locationLock.Unlock()
+
+	step := next.Sub(f.currentTime)
+	if step < f.granularity {
+		step = f.granularity
+	} else if step > realtimeTick {
+		step = realtimeTick
+	}
+
+	return step
+}
+
+// Anything held back for coalescing goes out as soon as there is room
+func (f *realtime) flushOutputs() {
+	f.outputWeather.flush()
+	f.outputRaceControlMessages.flush()
+	f.outputTimingMessages.flush()
+	f.outputEvent.flush()
+	f.outputTelemetry.flush()
+	f.outputLocation.flush()
+	f.outputRadio.flush()
+	f.outputDrivers.flush()
+}
+
+func (f *realtime) Dropped() map[Messages.StreamType]uint64 {
+	return f.drops.copy()
+}
+
+func (f *realtime) Snapshot() Messages.Snapshot {
+	return f.state.snapshot()
+}
+
+// IncrementDelay - Hold everything back for longer by moving the time back, nothing is sent until it catches up again
+func (f *realtime) IncrementDelay(delay time.Duration) {
+	f.control(func() {
+		f.delay += delay
+		if !f.currentTime.IsZero() {
+			f.currentTime = f.currentTime.Add(-delay)
+		}
+	})
+}
+
+// DecrementDelay - Catch up by moving the time forward, the delay can't go below zero
+func (f *realtime) DecrementDelay(delay time.Duration) {
+	f.control(func() {
+		if delay > f.delay {
+			delay = f.delay
+		}
+
+		f.delay -= delay
+		if !f.currentTime.IsZero() {
+			f.incrementTime += delay
+		}
+	})
+}
 
 func (f *realtime) Delay() time.Duration {
-	return 0
+	var delay time.Duration
+	f.control(func() { delay = f.delay })
+	return delay
 }
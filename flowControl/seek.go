@@ -0,0 +1,45 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package flowControl
+
+import "sync"
+
+// Lets anyone moving the data wait until the flow control has carried on from the new time
+type seekSignal struct {
+	next chan struct{}
+	lock sync.Mutex
+}
+
+// Closed once the next seek has been applied
+func (s *seekSignal) wait() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.next == nil {
+		s.next = make(chan struct{})
+	}
+	return s.next
+}
+
+func (s *seekSignal) applied() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.next != nil {
+		close(s.next)
+		s.next = nil
+	}
+}
@@ -0,0 +1,368 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package flowControl
+
+import (
+	"encoding/gob"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// The stream buffer writes envelopes to disk so gob needs to know everything that can be in them
+func init() {
+	gob.Register(Messages.Weather{})
+	gob.Register(Messages.RaceControlMessage{})
+	gob.Register(Messages.Timing{})
+	gob.Register(Messages.Event{})
+	gob.Register(Messages.Telemetry{})
+	gob.Register(Messages.Location{})
+	gob.Register(Messages.Radio{})
+	gob.Register(Messages.Drivers{})
+}
+
+// How much memory the buffers can use between them, zero means no limit
+type memoryBudget struct {
+	limit int
+	used  int
+	lock  sync.Mutex
+}
+
+func (m *memoryBudget) reserve(size int) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.limit > 0 && m.used+size > m.limit {
+		return false
+	}
+	m.used += size
+	return true
+}
+
+// Used even when it goes over the limit
+func (m *memoryBudget) force(size int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.used += size
+}
+
+func (m *memoryBudget) release(size int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.used -= size
+}
+
+func (m *memoryBudget) full() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.limit > 0 && m.used >= m.limit
+}
+
+// An estimate of the memory a message uses, the message itself plus the strings, slices, maps and interface values it
+// holds. Pointers are usually to shared data, like the time zone in a time.Time, so what they point to isn't counted.
+func messageSize[T any](msg T) int {
+	value := reflect.ValueOf(&msg).Elem()
+	return int(value.Type().Size()) + heapSize(value)
+}
+
+func heapSize(value reflect.Value) int {
+	switch value.Kind() {
+	case reflect.String:
+		return value.Len()
+
+	case reflect.Slice:
+		size := value.Cap() * int(value.Type().Elem().Size())
+		for x := 0; x < value.Len(); x++ {
+			size += heapSize(value.Index(x))
+		}
+		return size
+
+	case reflect.Array:
+		// Nothing to find in arrays of numbers
+		if value.Type().Elem().Kind() <= reflect.Complex128 {
+			return 0
+		}
+		size := 0
+		for x := 0; x < value.Len(); x++ {
+			size += heapSize(value.Index(x))
+		}
+		return size
+
+	case reflect.Struct:
+		size := 0
+		for x := 0; x < value.NumField(); x++ {
+			size += heapSize(value.Field(x))
+		}
+		return size
+
+	case reflect.Map:
+		size := 0
+		iter := value.MapRange()
+		for iter.Next() {
+			size += int(iter.Key().Type().Size()) + heapSize(iter.Key()) +
+				int(iter.Value().Type().Size()) + heapSize(iter.Value())
+		}
+		return size
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return 0
+		}
+		return int(value.Elem().Type().Size()) + heapSize(value.Elem())
+	}
+
+	return 0
+}
+
+// A message in memory with the size that was taken from the budget for it
+type bufferedMessage[T any] struct {
+	msg  T
+	size int
+}
+
+// Only messages from before the filter was added to a spillBuffer are checked when they are read back
+type spillFilter[T any] struct {
+	keep   func(msg T) bool
+	before int
+}
+
+// A queue that keeps messages in memory until the budget runs out then writes them to a temporary file until they are
+// needed. Messages always come out in the order they were added. It isn't thread safe, the caller locks it.
+type spillBuffer[T any] struct {
+	memory []bufferedMessage[T]
+	budget *memoryBudget
+
+	dir     string
+	file    *os.File
+	encoder *gob.Encoder
+	reader  *os.File
+	decoder *gob.Decoder
+	// How many messages are in the file and how many have been read back
+	spilled int
+	read    int
+	filters []spillFilter[T]
+
+	log *f1log.F1GopherLibLog
+}
+
+func createSpillBuffer[T any](budget *memoryBudget, dir string, log *f1log.F1GopherLibLog) *spillBuffer[T] {
+	return &spillBuffer[T]{
+		budget: budget,
+		dir:    dir,
+		log:    log,
+	}
+}
+
+func (s *spillBuffer[T]) add(msg T) {
+	size := messageSize(msg)
+
+	// Once anything is on disk everything after it has to be too to keep the order
+	if !s.onDisk() && s.budget.reserve(size) {
+		s.memory = append(s.memory, bufferedMessage[T]{msg: msg, size: size})
+		return
+	}
+
+	if s.spill(msg) {
+		return
+	}
+
+	// Better to go over the budget than lose data
+	s.budget.force(size)
+	s.memory = append(s.memory, bufferedMessage[T]{msg: msg, size: size})
+}
+
+// Reads back from the file when needed so there is always something to read when it isn't zero
+func (s *spillBuffer[T]) len() int {
+	for len(s.memory) == 0 && s.onDisk() {
+		s.readBack()
+	}
+
+	return len(s.memory) + s.spilled - s.read
+}
+
+// The oldest message, len must have been checked first
+func (s *spillBuffer[T]) front() T {
+	return s.memory[0].msg
+}
+
+func (s *spillBuffer[T]) pop() {
+	s.budget.release(s.memory[0].size)
+	s.memory = s.memory[1:]
+}
+
+func (s *spillBuffer[T]) clear() {
+	for _, buffered := range s.memory {
+		s.budget.release(buffered.size)
+	}
+	s.memory = nil
+	s.closeFile()
+}
+
+// Swaps the newest message that matches for msg. Only messages still in memory can be swapped and only when nothing
+// is on disk, otherwise a later message in the file could be older than msg, so false means msg still needs adding.
+func (s *spillBuffer[T]) replace(msg T, matches func(msg T) bool) bool {
+	if s.onDisk() {
+		return false
+	}
+
+	for x := len(s.memory) - 1; x >= 0; x-- {
+		if matches(s.memory[x].msg) {
+			size := messageSize(msg)
+			s.budget.release(s.memory[x].size)
+			s.budget.force(size)
+			s.memory[x] = bufferedMessage[T]{msg: msg, size: size}
+			return true
+		}
+	}
+
+	return false
+}
+
+// Removes the messages that keep returns false for, the ones on disk are removed as they are read back
+func (s *spillBuffer[T]) filter(keep func(msg T) bool) {
+	kept := s.memory[:0]
+	for _, buffered := range s.memory {
+		if keep(buffered.msg) {
+			kept = append(kept, buffered)
+		} else {
+			s.budget.release(buffered.size)
+		}
+	}
+	s.memory = kept
+
+	if s.onDisk() {
+		s.filters = append(s.filters, spillFilter[T]{keep: keep, before: s.spilled})
+	}
+}
+
+// Only sends the latest message for each key that is due, see coalesceDue. Messages still on disk aren't due yet.
+func (s *spillBuffer[T]) coalesce(currentTime time.Time, timestamp func(msg T) time.Time, key func(msg T) any) {
+	if s.len() == 0 {
+		return
+	}
+
+	used := 0
+	for _, buffered := range s.memory {
+		used += buffered.size
+	}
+
+	s.memory = coalesceDue(s.memory, currentTime,
+		func(buffered bufferedMessage[T]) time.Time { return timestamp(buffered.msg) },
+		func(buffered bufferedMessage[T]) any { return key(buffered.msg) })
+
+	for _, buffered := range s.memory {
+		used -= buffered.size
+	}
+	s.budget.release(used)
+}
+
+func (s *spillBuffer[T]) onDisk() bool {
+	return s.read < s.spilled
+}
+
+func (s *spillBuffer[T]) spill(msg T) bool {
+	if s.file == nil {
+		file, err := os.CreateTemp(s.dir, "f1gopherlib-*.spill")
+		if err != nil {
+			s.log.Errorf("Creating buffer spill file: %v", err)
+			return false
+		}
+
+		reader, err := os.Open(file.Name())
+		if err != nil {
+			s.log.Errorf("Opening buffer spill file: %v", err)
+			file.Close()
+			os.Remove(file.Name())
+			return false
+		}
+
+		s.file = file
+		s.encoder = gob.NewEncoder(file)
+		s.reader = reader
+		s.decoder = gob.NewDecoder(reader)
+	}
+
+	err := s.encoder.Encode(msg)
+	if err != nil {
+		s.log.Errorf("Writing to buffer spill file: %v", err)
+		return false
+	}
+
+	s.spilled++
+	return true
+}
+
+// Reads messages back from the file until the budget is used up, always at least one when there is one to keep
+func (s *spillBuffer[T]) readBack() {
+	for s.onDisk() {
+		if len(s.memory) > 0 && s.budget.full() {
+			break
+		}
+
+		var msg T
+		err := s.decoder.Decode(&msg)
+		if err != nil {
+			// Without the file everything spilled is lost
+			s.log.Errorf("Reading from buffer spill file: %v", err)
+			s.closeFile()
+			return
+		}
+
+		index := s.read
+		s.read++
+		if s.filtered(index, msg) {
+			continue
+		}
+
+		size := messageSize(msg)
+		s.budget.force(size)
+		s.memory = append(s.memory, bufferedMessage[T]{msg: msg, size: size})
+	}
+
+	if !s.onDisk() {
+		s.closeFile()
+	}
+}
+
+func (s *spillBuffer[T]) filtered(index int, msg T) bool {
+	for _, filter := range s.filters {
+		if index < filter.before && !filter.keep(msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *spillBuffer[T]) closeFile() {
+	if s.file != nil {
+		s.reader.Close()
+		s.file.Close()
+		os.Remove(s.file.Name())
+	}
+
+	s.file = nil
+	s.encoder = nil
+	s.reader = nil
+	s.decoder = nil
+	s.spilled = 0
+	s.read = 0
+	s.filters = nil
+}
@@ -0,0 +1,129 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package flowControl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+)
+
+// The latest of everything that has been sent so it can be asked for at any time
+type state struct {
+	timestamp   time.Time
+	event       Messages.Event
+	timing      map[int]Messages.Timing
+	weather     Messages.Weather
+	location    map[int]Messages.Location
+	drivers     Messages.Drivers
+	raceControl []Messages.RaceControlMessage
+
+	lock sync.Mutex
+}
+
+func (s *state) setTime(timestamp time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.timestamp = timestamp
+}
+
+func (s *state) setEvent(event Messages.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.event = event
+}
+
+func (s *state) setTiming(timing Messages.Timing) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.timing == nil {
+		s.timing = make(map[int]Messages.Timing)
+	}
+	s.timing[timing.Number] = timing
+}
+
+func (s *state) setWeather(weather Messages.Weather) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.weather = weather
+}
+
+func (s *state) setLocation(location Messages.Location) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.location == nil {
+		s.location = make(map[int]Messages.Location)
+	}
+	s.location[location.DriverNumber] = location
+}
+
+func (s *state) setDrivers(drivers Messages.Drivers) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.drivers = drivers
+}
+
+func (s *state) addRaceControl(raceControl Messages.RaceControlMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.raceControl = append(s.raceControl, raceControl)
+}
+
+// Race control messages from after the target haven't happened yet, everything else is replaced by the data sent after
+// moving
+func (s *state) seek(target time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for x := range s.raceControl {
+		if !s.raceControl[x].Timestamp.Before(target) {
+			s.raceControl = s.raceControl[:x]
+			break
+		}
+	}
+}
+
+// A copy that can't be changed by anything sent later
+func (s *state) snapshot() Messages.Snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := Messages.Snapshot{
+		Timestamp:   s.timestamp,
+		Event:       s.event,
+		Timing:      make(map[int]Messages.Timing, len(s.timing)),
+		Weather:     s.weather,
+		Location:    make(map[int]Messages.Location, len(s.location)),
+		Drivers:     Messages.Drivers{Timestamp: s.drivers.Timestamp},
+		RaceControl: append([]Messages.RaceControlMessage(nil), s.raceControl...),
+	}
+
+	for number, timing := range s.timing {
+		timing.PitStopTimes = append([]Messages.PitStop(nil), timing.PitStopTimes...)
+		result.Timing[number] = timing
+	}
+
+	for number, location := range s.location {
+		result.Location[number] = location
+	}
+
+	result.Drivers.Drivers = append([]Messages.DriverInfo(nil), s.drivers.Drivers...)
+
+	return result
+}
@@ -16,6 +16,8 @@
 package flowControl
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/stephenhoran/f1gopherlib/Messages"
@@ -31,8 +33,41 @@ type straightThrough struct {
 	outputEventTime           chan<- Messages.EventTime
 	outputRadio               chan<- Messages.Radio
 	outputDrivers             chan<- Messages.Drivers
+	timingDeltas              *timingDeltas
 
-	isPaused bool
+	// When set everything is also sent here, each send waits to be read
+	outputStream   chan<- Messages.Envelope
+	streamSequence uint64
+
+	// Subscription handlers can be slow so they follow their own policy instead of waiting
+	subscriptions     *output[Messages.Envelope]
+	subscriptionsLock sync.Mutex
+	policies          Backpressure
+	drops             *drops
+
+	state state
+
+	isPaused     bool
+	isPausedLock sync.Mutex
+
+	// Messages from before this are old news after moving the data to a new time
+	seekTarget time.Time
+	seeks      seekSignal
+
+	// Everything is held back for the delay after it arrives
+	delay     time.Duration
+	delayed   []delayedSend
+	releasing bool
+	delayLock sync.Mutex
+	wake      chan struct{}
+
+	ctx context.Context
+	wg  *sync.WaitGroup
+}
+
+type delayedSend struct {
+	added time.Time
+	send  func()
 }
 
 func (f *straightThrough) Run() {
@@ -40,37 +75,190 @@ func (f *straightThrough) Run() {
 }
 
 func (f *straightThrough) AddWeather(weather Messages.Weather) {
-	f.outputWeather <- weather
+	f.output(func() {
+		f.state.setWeather(weather)
+
+		f.outputWeather <- weather
+		f.sendEnvelope(Messages.WeatherStream, weather.Timestamp, weather)
+	})
 }
 
 func (f *straightThrough) AddRaceControlMessage(raceControlMessage Messages.RaceControlMessage) {
-	f.outputRaceControlMessages <- raceControlMessage
+	if raceControlMessage.Timestamp.Before(f.seekTarget) {
+		return
+	}
+
+	f.output(func() {
+		f.state.addRaceControl(raceControlMessage)
+
+		f.outputRaceControlMessages <- raceControlMessage
+		f.sendEnvelope(Messages.RaceControlStream, raceControlMessage.Timestamp, raceControlMessage)
+	})
 }
 
 func (f *straightThrough) AddTiming(timing Messages.Timing) {
-	f.outputTimingMessages <- timing
+	f.output(func() {
+		f.state.setTiming(timing)
+
+		f.outputTimingMessages <- timing
+		f.timingDeltas.send(f.ctx, timing)
+		f.sendEnvelope(Messages.TimingStream, timing.Timestamp, timing)
+	})
 }
 
 func (f *straightThrough) AddEvent(event Messages.Event) {
-	f.outputEvent <- event
+	f.output(func() {
+		eventTime := Messages.EventTime{Timestamp: event.Timestamp}
+		f.state.setEvent(event)
+		f.state.setTime(event.Timestamp)
+
+		f.outputEvent <- event
+		f.sendEnvelope(Messages.EventStream, event.Timestamp, event)
 
-	f.outputEventTime <- Messages.EventTime{Timestamp: event.Timestamp}
+		f.outputEventTime <- eventTime
+		f.sendEnvelope(Messages.EventTimeStream, eventTime.Timestamp, eventTime)
+	})
 }
 
 func (f *straightThrough) AddTelemetry(telemetry Messages.Telemetry) {
-	f.outputTelemetry <- telemetry
+	if telemetry.Timestamp.Before(f.seekTarget) {
+		return
+	}
+
+	f.output(func() {
+		f.outputTelemetry <- telemetry
+		f.sendEnvelope(Messages.TelemetryStream, telemetry.Timestamp, telemetry)
+	})
 }
 
 func (f *straightThrough) AddLocation(location Messages.Location) {
-	f.outputLocation <- location
+	if location.Timestamp.Before(f.seekTarget) {
+		return
+	}
+
+	f.output(func() {
+		f.state.setLocation(location)
+
+		f.outputLocation <- location
+		f.sendEnvelope(Messages.LocationStream, location.Timestamp, location)
+	})
 }
 
 func (f *straightThrough) AddRadio(radio Messages.Radio) {
-	f.outputRadio <- radio
+	if radio.Timestamp.Before(f.seekTarget) {
+		return
+	}
+
+	f.output(func() {
+		f.outputRadio <- radio
+		f.sendEnvelope(Messages.RadioStream, radio.Timestamp, radio)
+	})
 }
 
 func (f *straightThrough) AddDrivers(drivers Messages.Drivers) {
-	f.outputDrivers <- drivers
+	f.output(func() {
+		f.state.setDrivers(drivers)
+
+		f.outputDrivers <- drivers
+		f.sendEnvelope(Messages.DriversStream, drivers.Timestamp, drivers)
+	})
+}
+
+// Sends straight away unless there is a delay or something is still waiting, then it is sent once it has been held back
+// for the delay
+func (f *straightThrough) output(send func()) {
+	f.delayLock.Lock()
+	if f.delay == 0 && len(f.delayed) == 0 {
+		f.delayLock.Unlock()
+		send()
+		return
+	}
+
+	f.delayed = append(f.delayed, delayedSend{added: time.Now(), send: send})
+	if !f.releasing {
+		f.releasing = true
+		f.wg.Add(1)
+		go f.release()
+	}
+	f.delayLock.Unlock()
+
+	f.wakeRelease()
+}
+
+// Sends what is waiting in order once each has been held back for the delay, until there is nothing left
+func (f *straightThrough) release() {
+	defer f.wg.Done()
+
+	for {
+		f.delayLock.Lock()
+		if len(f.delayed) == 0 {
+			f.releasing = false
+			f.delayLock.Unlock()
+			return
+		}
+		next := f.delayed[0]
+		wait := time.Until(next.added.Add(f.delay))
+		f.delayLock.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-f.wake:
+				// The delay has changed so work out the wait again
+				timer.Stop()
+			case <-f.ctx.Done():
+				timer.Stop()
+				return
+			}
+			continue
+		}
+
+		// Only removed once sent so nothing new can be sent straight away before it
+		next.send()
+
+		f.delayLock.Lock()
+		f.delayed = f.delayed[1:]
+		f.delayLock.Unlock()
+	}
+}
+
+func (f *straightThrough) wakeRelease() {
+	select {
+	case f.wake <- struct{}{}:
+	default:
+		// Already waking
+	}
+}
+
+// Only called from one goroutine at a time so the sequence doesn't need locking
+func (f *straightThrough) sendEnvelope(streamType Messages.StreamType, timestamp time.Time, data any) {
+	f.subscriptionsLock.Lock()
+	subscriptions := f.subscriptions
+	f.subscriptionsLock.Unlock()
+
+	if f.outputStream == nil && subscriptions == nil {
+		return
+	}
+
+	f.streamSequence++
+	envelope := Messages.Envelope{Sequence: f.streamSequence, Type: streamType, Timestamp: timestamp, Data: data}
+
+	if f.outputStream != nil {
+		f.outputStream <- envelope
+	}
+	if subscriptions != nil {
+		subscriptions.send(f.ctx, envelope)
+	}
+}
+
+// SendToSubscriptions - Also send everything from now on to output, following the SubscriptionStream policy
+func (f *straightThrough) SendToSubscriptions(output chan Messages.Envelope) {
+	f.subscriptionsLock.Lock()
+	defer f.subscriptionsLock.Unlock()
+
+	f.subscriptions = createOutput(output, Messages.SubscriptionStream, f.policies, f.drops, nil,
+		noDriver[Messages.Envelope])
 }
 
 func (f *straightThrough) IncrementLap() {}
@@ -80,17 +268,66 @@ func (f *straightThrough) IncrementTime(duration time.Duration) {}
 func (f *straightThrough) SkipToSessionStart(start time.Time) {}
 
 func (f *straightThrough) TogglePause() {
+	f.isPausedLock.Lock()
+	defer f.isPausedLock.Unlock()
+
 	f.isPaused = !f.isPaused
 }
 
 func (f *straightThrough) IsPaused() bool {
+	f.isPausedLock.Lock()
+	defer f.isPausedLock.Unlock()
+
 	return f.isPaused
 }
 
-func (f *straightThrough) IncrementDelay(delay time.Duration) {}
+// Everything is sent as soon as it arrives so the speed is set by the connection
+func (f *straightThrough) SetSpeed(speed float64) {}
 
-func (f *straightThrough) DecrementDelay(delay time.Duration) {}
+// Called from the parser which also calls all of the Add functions so no locking needed
+func (f *straightThrough) SeekTo(target time.Time) {
+	f.seekTarget = target
+	f.state.seek(target)
+	f.seeks.applied()
+}
+
+// NextSeek - Closed once the next SeekTo has been applied
+func (f *straightThrough) NextSeek() <-chan struct{} {
+	return f.seeks.wait()
+}
+
+// Everything else waits to be read so only the subscriptions can drop messages
+func (f *straightThrough) Dropped() map[Messages.StreamType]uint64 {
+	return f.drops.copy()
+}
+
+func (f *straightThrough) Snapshot() Messages.Snapshot {
+	return f.state.snapshot()
+}
+
+func (f *straightThrough) IncrementDelay(delay time.Duration) {
+	f.delayLock.Lock()
+	f.delay += delay
+	f.delayLock.Unlock()
+
+	f.wakeRelease()
+}
+
+// DecrementDelay - The delay can't go below zero
+func (f *straightThrough) DecrementDelay(delay time.Duration) {
+	f.delayLock.Lock()
+	f.delay -= delay
+	if f.delay < 0 {
+		f.delay = 0
+	}
+	f.delayLock.Unlock()
+
+	f.wakeRelease()
+}
 
 func (f *straightThrough) Delay() time.Duration {
-	return 0
+	f.delayLock.Lock()
+	defer f.delayLock.Unlock()
+
+	return f.delay
 }
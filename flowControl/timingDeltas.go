@@ -0,0 +1,82 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package flowControl
+
+import (
+	"context"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+// Works out what has changed for a driver since their last delta was sent
+type timingDeltas struct {
+	output *output[Messages.TimingDelta]
+	sent   map[int]Messages.Timing
+	log    *f1log.F1GopherLibLog
+}
+
+func createTimingDeltas(
+	channel chan Messages.TimingDelta,
+	policies Backpressure,
+	drops *drops,
+	commands <-chan command,
+	log *f1log.F1GopherLibLog) *timingDeltas {
+
+	if channel == nil {
+		return nil
+	}
+
+	// A delta that has already been worked out can't be thrown away because the next one only has what changed
+	// since it, so only the newest delta is dropped and its changes come with the driver's next one instead
+	policy := policies[Messages.TimingDeltaStream]
+	if policy == DropOldest || policy == CoalesceLatest {
+		log.Warnf("Timing deltas can't use the %s backpressure policy, using %s instead", policy, DropNewest)
+		policy = DropNewest
+	}
+
+	return &timingDeltas{
+		output: createOutput(channel, Messages.TimingDeltaStream, Backpressure{Messages.TimingDeltaStream: policy}, drops,
+			commands, func(msg Messages.TimingDelta) any { return msg.Number }),
+		sent: make(map[int]Messages.Timing),
+		log:  log,
+	}
+}
+
+func (d *timingDeltas) send(ctx context.Context, timing Messages.Timing) {
+	if d == nil {
+		return
+	}
+
+	delta, err := Messages.CreateTimingDelta(d.sent[timing.Number], timing)
+	if err != nil {
+		d.log.Errorf("Timing delta for %d: %v", timing.Number, err)
+		return
+	}
+
+	if len(delta.Changes) == 0 {
+		return
+	}
+
+	if !d.output.send(ctx, delta) {
+		// The next delta for the driver will have these changes too
+		return
+	}
+
+	// The parser updates the pit stops in place
+	timing.PitStopTimes = append([]Messages.PitStop(nil), timing.PitStopTimes...)
+	d.sent[timing.Number] = timing
+}
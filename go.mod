@@ -3,12 +3,9 @@ module github.com/stephenhoran/f1gopherlib
 go 1.23
 
 require (
+	github.com/cenkalti/backoff/v4 v4.3.0
 	github.com/f1gopher/signalr/v2 v2.0.0-20221210121059-1985aaf5fb97
+	github.com/gorilla/websocket v1.5.3
 	github.com/zsefvlol/timezonemapper v1.0.0
 	golang.org/x/sync v0.8.0
 )
-
-require (
-	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-)
@@ -0,0 +1,258 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package f1gopherlib
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+)
+
+// Option - Optional settings that can be passed when creating a session
+type Option func(*options)
+
+type options struct {
+	baseUrl     string
+	httpClient  *http.Client
+	archiveFile string
+	recordCache string
+	signalrCore bool
+	liveUrl     string
+	liveEvent   *RaceEvent
+
+	keyframeInterval time.Duration
+	keyframeFile     string
+
+	batch  bool
+	cache  string
+	stream bool
+
+	backpressure flowControl.Backpressure
+	coalesce     bool
+	granularity  time.Duration
+	timingDeltas bool
+
+	memoryLimit int
+	spillDir    string
+
+	delay time.Duration
+
+	dvr    bool
+	dvrDir string
+
+	backfill bool
+
+	// Send the telemetry for every driver from the start
+	allTelemetry bool
+}
+
+// How often the parser state is saved while replaying
+const defaultKeyframeInterval = time.Minute
+
+func createOptions(opts []Option) options {
+	result := options{
+		baseUrl:          DefaultBaseUrl,
+		httpClient:       nil,
+		keyframeInterval: defaultKeyframeInterval,
+	}
+
+	for _, opt := range opts {
+		opt(&result)
+	}
+
+	return result
+}
+
+func (o options) liveUrlOr(defaultUrl string) string {
+	if len(o.liveUrl) > 0 {
+		return o.liveUrl
+	}
+	return defaultUrl
+}
+
+// WithBaseUrl - Download the static session data and assets from the given url instead of the official
+// live timing server. The url replaces the DefaultBaseUrl part of the event url so a mirror needs to use the
+// same layout.
+func WithBaseUrl(url string) Option {
+	return func(o *options) {
+		o.baseUrl = url
+	}
+}
+
+// WithHttpClient - Use the given client for all requests instead of the http.DefaultClient
+func WithHttpClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithArchive - Record everything received from a live session into an archive that can be replayed using
+// CreateDebugReplay. A timestamp and extension are added to the file name.
+func WithArchive(file string) Option {
+	return func(o *options) {
+		o.archiveFile = file
+	}
+}
+
+// WithRecording - Record everything received from a live session as '<Topic>.jsonStream' files in the same layout
+// as the static replay data. The files are written to where CreateReplay caches the session when given the same
+// cache folder, so the session can be replayed later without downloading anything.
+func WithRecording(cache string) Option {
+	return func(o *options) {
+		o.recordCache = cache
+	}
+}
+
+// WithSignalRCore - Connect to live sessions using the SignalR Core hub instead of the classic SignalR endpoint
+func WithSignalRCore() Option {
+	return func(o *options) {
+		o.signalrCore = true
+	}
+}
+
+// WithLiveUrl - Connect to live sessions using the given url instead of the official live timing server. Use the
+// SignalR Core hub url if WithSignalRCore is also used.
+func WithLiveUrl(url string) Option {
+	return func(o *options) {
+		o.liveUrl = url
+	}
+}
+
+// WithLiveEvent - Treat the given event as the one happening live instead of finding it in the schedule. Useful
+// when connecting to a server that replays a recorded session.
+func WithLiveEvent(event RaceEvent) Option {
+	return func(o *options) {
+		o.liveEvent = &event
+	}
+}
+
+// WithKeyframeInterval - How often the state is saved while replaying, or recording WithDvr, so seeking only needs to
+// process the data since the nearest keyframe. The default is every minute of session time and zero turns keyframes off.
+func WithKeyframeInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.keyframeInterval = interval
+	}
+}
+
+// WithKeyframeFile - Load the keyframes for a replay from file and save them back when the session is closed, so a
+// later session can jump straight to any point that has been replayed before. Replays using a cache save them in the
+// cache folder by default.
+func WithKeyframeFile(file string) Option {
+	return func(o *options) {
+		o.keyframeFile = file
+	}
+}
+
+// WithBatch - Replays send all of the data in timestamp order as fast as it is read instead of in realtime. The data
+// always goes straight through so nothing is dropped and it is only as fast as the slowest channel is read.
+func WithBatch() Option {
+	return func(o *options) {
+		o.batch = true
+	}
+}
+
+// WithStream - Also send every message to Stream, in the order it was processed, so the data only needs reading from
+// one channel. Straight through sessions wait for each message to be read from both Stream and its own channel.
+func WithStream() Option {
+	return func(o *options) {
+		o.stream = true
+	}
+}
+
+// WithBackpressure - What to do when the channel for a stream is full because it isn't read fast enough. Only used by
+// realtime sessions apart from SubscriptionStream, the default is to drop the newest message. Dropped counts what has
+// been lost.
+func WithBackpressure(streamType Messages.StreamType, policy flowControl.Policy) Option {
+	return func(o *options) {
+		if o.backpressure == nil {
+			o.backpressure = make(flowControl.Backpressure)
+		}
+		o.backpressure[streamType] = policy
+	}
+}
+
+// WithCoalescing - Realtime sessions only send the latest timing for each driver and the latest event each time the data
+// is sent instead of every update, for when the data is only shown at a fixed rate. Stream still has every update.
+func WithCoalescing() Option {
+	return func(o *options) {
+		o.coalesce = true
+	}
+}
+
+// WithTimingDeltas - Also send what has changed in each driver's timing to TimingDeltas. Realtime sessions use the
+// TimingDeltaStream backpressure policy and when DropNewest drops a delta its changes are sent with the driver's next one.
+// Only Block and DropNewest can be used for the deltas, DropOldest and CoalesceLatest would lose changes that are never
+// sent again so they use DropNewest instead.
+func WithTimingDeltas() Option {
+	return func(o *options) {
+		o.timingDeltas = true
+	}
+}
+
+// WithMemoryLimit - Realtime sessions keep the timing, telemetry, locations and stream waiting to be sent within limit
+// bytes of memory, anything more is written to a temporary file in dir until it is needed. An empty dir uses the default
+// temporary folder. The size of each message is an estimate and the weather, race control messages, events, radio
+// messages and driver lists aren't counted as there are only a few of them.
+func WithMemoryLimit(limit int, dir string) Option {
+	return func(o *options) {
+		o.memoryLimit = limit
+		o.spillDir = dir
+	}
+}
+
+// WithPrecisePlayback - Realtime sessions send each message at its own time instead of in bursts every couple of
+// seconds. Messages that are due within granularity of each other are sent together.
+func WithPrecisePlayback(granularity time.Duration) Option {
+	return func(o *options) {
+		o.granularity = granularity
+	}
+}
+
+// WithDelay - Hold a live session back by delay from the start, see IncrementDelay
+func WithDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.delay = delay
+	}
+}
+
+// WithDvr - Live sessions keep everything received in a temporary file in dir so they can be paused, moved back with
+// SeekTo, SeekToLap or SeekToRemaining and then CatchUpToLive. The data is sent at the time it happened instead of as soon as it
+// arrives. An empty dir uses the default temporary folder.
+func WithDvr(dir string) Option {
+	return func(o *options) {
+		o.dvr = true
+		o.dvrDir = dir
+	}
+}
+
+// WithBackfill - Live sessions joined after they have started first send everything that has already happened, read
+// from the static data for the event, then carry on with the live data. Without it only the current state is sent when
+// joining.
+func WithBackfill() Option {
+	return func(o *options) {
+		o.backfill = true
+	}
+}
+
+// WithCache - Where LoadSession saves the data it downloads so loading the session again doesn't need to download
+// it. It uses the same layout as the cache given to CreateReplay.
+func WithCache(cache string) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
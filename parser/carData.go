@@ -38,7 +38,9 @@ func (p *Parser) parseCarData(dat map[string]interface{}, timestamp time.Time) (
 		}
 		localTimestamp := utcTimestamp.In(p.timezone)
 
-		for driverId, car := range record.(map[string]interface{})["Cars"].(map[string]interface{}) {
+		cars := record.(map[string]interface{})["Cars"].(map[string]interface{})
+		for _, driverId := range orderedKeys(cars) {
+			car := cars[driverId]
 			driverNum, _ := strconv.Atoi(driverId)
 
 			t := Messages.Telemetry{
@@ -90,6 +92,7 @@ func (p *Parser) parseCarData(dat map[string]interface{}, timestamp time.Time) (
 			// Only send the telemetry info if has been requested for this driver
 			p.sendTelemetryLock.Lock()
 			_, sendTelemetry := p.sendTelemetryFor[driverNum]
+			sendTelemetry = sendTelemetry || p.sendAllTelemetry
 			p.sendTelemetryLock.Unlock()
 			if sendTelemetry {
 				result = append(result, t)
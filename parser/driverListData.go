@@ -28,7 +28,8 @@ import (
 func (p *Parser) parseDriverList(dat map[string]interface{}, timestamp time.Time) []Messages.Drivers {
 	var driver []Messages.Drivers = nil
 
-	for driverNum, info := range dat {
+	for _, driverNum := range orderedKeys(dat) {
+		info := dat[driverNum]
 		if driverNum == "_kf" {
 			continue
 		}
@@ -57,7 +58,7 @@ func (p *Parser) parseDriverList(dat map[string]interface{}, timestamp time.Time
 			if colorExists {
 				_, err := fmt.Sscanf(teamHexColour, "%02x%02x%02x", &teamColor.R, &teamColor.G, &teamColor.B)
 				if err != nil {
-					p.ParseErrorf(connection.DriverListFile, timestamp, "Unable to parse team color: '%s', %v", teamColor, err)
+					p.ParseErrorf(connection.DriverListFile, timestamp, "Unable to parse team color: '%s', %v", teamHexColour, err)
 				}
 			}
 
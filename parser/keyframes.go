@@ -0,0 +1,172 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+)
+
+// State - Everything the parser has worked out from the data up to and including Timestamp
+type State struct {
+	Timestamp   time.Time                  `json:"timestamp"`
+	DriverTimes map[string]Messages.Timing `json:"driver_times"`
+	Event       Messages.Event             `json:"event"`
+}
+
+// Keyframes - The parser state saved at regular points through a session so moving to a different time only needs
+// the data since the nearest keyframe to be processed again
+type Keyframes struct {
+	requestedData DataSource
+	interval      time.Duration
+
+	states []State
+	lock   sync.Mutex
+}
+
+// The file layout, the requested data is saved because the state only contains what was asked for
+type keyframesFile struct {
+	RequestedData DataSource    `json:"requested_data"`
+	Interval      time.Duration `json:"interval"`
+	States        []State       `json:"states"`
+}
+
+// CreateKeyframes - An empty index that will save the state every interval of session time
+func CreateKeyframes(requestedData DataSource, interval time.Duration) *Keyframes {
+	return &Keyframes{
+		requestedData: requestedData,
+		interval:      interval,
+	}
+}
+
+// LoadKeyframes - Read an index saved by Save. It can only be used for the same requested data.
+func LoadKeyframes(file string, requestedData DataSource) (*Keyframes, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved keyframesFile
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.RequestedData != requestedData {
+		return nil, fmt.Errorf("keyframes were saved for different data: %d, expected %d", saved.RequestedData, requestedData)
+	}
+
+	return &Keyframes{
+		requestedData: saved.RequestedData,
+		interval:      saved.Interval,
+		states:        saved.States,
+	}, nil
+}
+
+// Save - Write the index to file so a later session can carry on from any of the keyframes
+func (k *Keyframes) Save(file string) error {
+	k.lock.Lock()
+	data, err := json.Marshal(keyframesFile{
+		RequestedData: k.requestedData,
+		Interval:      k.interval,
+		States:        k.states,
+	})
+	k.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0644)
+}
+
+// Before - The time of the latest keyframe at or before target
+func (k *Keyframes) Before(target time.Time) (time.Time, bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	index := sort.Search(len(k.states), func(i int) bool { return k.states[i].Timestamp.After(target) })
+	if index == 0 {
+		return time.Time{}, false
+	}
+
+	return k.states[index-1].Timestamp, true
+}
+
+// Len - How many keyframes there are
+func (k *Keyframes) Len() int {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return len(k.states)
+}
+
+// The state saved at exactly timestamp
+func (k *Keyframes) at(timestamp time.Time) (State, bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	index := sort.Search(len(k.states), func(i int) bool { return !k.states[i].Timestamp.Before(timestamp) })
+	if index == len(k.states) || !k.states[index].Timestamp.Equal(timestamp) {
+		return State{}, false
+	}
+
+	return k.states[index], true
+}
+
+// Saves the state if there isn't already a keyframe within the interval before it. It is only called with the
+// parser state so doesn't need copying until it is added.
+func (k *Keyframes) add(state State) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	index := sort.Search(len(k.states), func(i int) bool { return k.states[i].Timestamp.After(state.Timestamp) })
+	if index > 0 && state.Timestamp.Sub(k.states[index-1].Timestamp) < k.interval {
+		return
+	}
+
+	state = copyState(state)
+	k.states = append(k.states, State{})
+	copy(k.states[index+1:], k.states[index:])
+	k.states[index] = state
+}
+
+// The timing holds slices that the parser updates in place so they need copying too
+func copyState(state State) State {
+	result := State{
+		Timestamp:   state.Timestamp,
+		DriverTimes: make(map[string]Messages.Timing, len(state.DriverTimes)),
+		Event:       state.Event,
+	}
+
+	for number, timing := range state.DriverTimes {
+		timing.PitStopTimes = append([]Messages.PitStop(nil), timing.PitStopTimes...)
+		result.DriverTimes[number] = timing
+	}
+
+	return result
+}
@@ -29,7 +29,9 @@ func (p *Parser) parseSessionDataData(dat map[string]interface{}, timestamp time
 	var result []Messages.Event
 
 	if dat["Series"] != nil && reflect.TypeOf(dat["Series"]).Kind() == reflect.Map {
-		for _, series := range dat["Series"].(map[string]interface{}) {
+		allSeries := dat["Series"].(map[string]interface{})
+		for _, key := range orderedKeys(allSeries) {
+			series := allSeries[key]
 
 			var text string
 			msg, exists := series.(map[string]interface{})["SessionStatus"]
@@ -68,7 +70,9 @@ func (p *Parser) parseSessionDataData(dat map[string]interface{}, timestamp time
 		}
 	} else {
 		if reflect.TypeOf(dat["StatusSeries"]).Kind() == reflect.Map {
-			for _, series := range dat["StatusSeries"].(map[string]interface{}) {
+			allSeries := dat["StatusSeries"].(map[string]interface{})
+			for _, key := range orderedKeys(allSeries) {
+				series := allSeries[key]
 				time := series.(map[string]interface{})["Utc"].(string)
 
 				value, err := parseTime(time)
@@ -23,6 +23,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -56,6 +58,12 @@ type Parser struct {
 	driverTimes map[string]Messages.Timing
 	eventState  Messages.Event
 
+	// Where the state is saved as the data is processed so it can be restored later
+	keyframes *Keyframes
+
+	// Closed once there is no more data to process
+	done chan struct{}
+
 	assets connection.AssetStore
 
 	session  Messages.SessionType
@@ -67,6 +75,7 @@ type Parser struct {
 	wg  *sync.WaitGroup
 
 	sendTelemetryFor  map[int]bool
+	sendAllTelemetry  bool
 	sendTelemetryLock sync.Mutex
 }
 
@@ -101,13 +110,14 @@ func Create(
 		timezone:         timezone,
 		log:              log,
 		sendTelemetryFor: nil,
+		done:             make(chan struct{}),
 	}
 
 	return &abc
 }
 
 func (p *Parser) ParseErrorf(file string, timestamp time.Time, msg string, a ...any) {
-	p.log.Errorf("%s - %v: %s", file, timestamp, fmt.Sprintf(msg, a))
+	p.log.Errorf("%s - %v: %s", file, timestamp, fmt.Sprintf(msg, a...))
 }
 
 func (p *Parser) ParseTimeError(file string, timestamp time.Time, field string, err error) {
@@ -132,9 +142,91 @@ func (p *Parser) SelectTelemetrySources(drivers []int) {
 	p.sendTelemetryFor = tmp
 }
 
+// SetKeyframes - Save the state to keyframes as the data is processed and restore it from them when the data is
+// moved to a keyframe. Must be called before Process.
+func (p *Parser) SetKeyframes(keyframes *Keyframes) {
+	p.keyframes = keyframes
+}
+
+func (p *Parser) resetState() {
+	p.driverTimes = make(map[string]Messages.Timing)
+	p.eventState = Messages.Event{}
+}
+
+// Carries on from the state saved at the keyframe and sends it so everything is up to date without the data
+// from before it
+func (p *Parser) restoreKeyframe(timestamp string) {
+	keyframeTime, err := parseTime(timestamp)
+	if err != nil {
+		p.log.Errorf("Parsing keyframe timestamp with value '%s': %v", timestamp, err)
+		p.resetState()
+		return
+	}
+
+	var state State
+	exists := false
+	if p.keyframes != nil {
+		state, exists = p.keyframes.at(keyframeTime)
+	}
+	if !exists {
+		p.log.Errorf("No keyframe for '%s', the data before it will be missing", timestamp)
+		p.resetState()
+		return
+	}
+
+	state = copyState(state)
+	p.driverTimes = state.DriverTimes
+	p.eventState = state.Event
+
+	timing := make([]Messages.Timing, 0, len(p.driverTimes))
+	for _, number := range orderedKeys(p.driverTimes) {
+		timing = append(timing, p.driverTimes[number])
+	}
+	sort.SliceStable(timing, func(i, j int) bool { return timing[i].Position < timing[j].Position })
+
+	if p.requestedData&Drivers == Drivers && len(timing) > 0 {
+		drivers := Messages.Drivers{Timestamp: state.Timestamp}
+		for _, driver := range timing {
+			drivers.Drivers = append(drivers.Drivers, Messages.DriverInfo{
+				StartPosition: driver.Position,
+				Name:          driver.Name,
+				ShortName:     driver.ShortName,
+				Number:        driver.Number,
+				Team:          driver.Team,
+				HexColor:      driver.HexColor,
+				Color:         driver.Color,
+			})
+		}
+		p.output.AddDrivers(drivers)
+	}
+
+	if p.requestedData&Timing == Timing {
+		for _, driver := range timing {
+			p.output.AddTiming(driver)
+		}
+	}
+
+	if p.requestedData&Event == Event {
+		p.output.AddEvent(p.eventState)
+	}
+}
+
+// Done - Closed when Process has finished because all of the data has been processed or it was shut down
+func (p *Parser) Done() <-chan struct{} {
+	return p.done
+}
+
+// SelectAllTelemetry - Send the telemetry for every driver instead of only the ones chosen by SelectTelemetrySources
+func (p *Parser) SelectAllTelemetry() {
+	p.sendTelemetryLock.Lock()
+	defer p.sendTelemetryLock.Unlock()
+	p.sendAllTelemetry = true
+}
+
 func (p *Parser) Process() {
 	p.wg.Add(1)
 	defer p.wg.Done()
+	defer close(p.done)
 
 	for {
 		select {
@@ -146,6 +238,44 @@ func (p *Parser) Process() {
 			case connection.EndOfDataFile:
 				return
 
+			case connection.SeekFile:
+				target, err := parseTime(msg.Timestamp)
+				if err != nil {
+					p.log.Errorf("Parsing seek timestamp with value '%s': %v", msg.Timestamp, err)
+					continue
+				}
+
+				p.output.SeekTo(target)
+
+				switch string(msg.Data) {
+				case "":
+					// Carrying on from where we are so the state is still valid
+
+				case connection.SeekRestart:
+					// Starting again from the beginning so rebuild the state from scratch
+					p.resetState()
+
+				default:
+					p.restoreKeyframe(string(msg.Data))
+				}
+
+			case connection.KeyframeFile:
+				if p.keyframes == nil {
+					continue
+				}
+
+				timestamp, err := parseTime(msg.Timestamp)
+				if err != nil {
+					p.log.Errorf("Parsing keyframe timestamp with value '%s': %v", msg.Timestamp, err)
+					continue
+				}
+
+				p.keyframes.add(State{
+					Timestamp:   timestamp,
+					DriverTimes: p.driverTimes,
+					Event:       p.eventState,
+				})
+
 			case connection.CatchupFile:
 				var dat map[string]interface{}
 				if err := json.Unmarshal([]byte(msg.Data), &dat); err != nil {
@@ -153,7 +283,17 @@ func (p *Parser) Process() {
 					continue
 				}
 
-				zeroTimestamp := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+				// The initial catchup has no timestamp but one after a live reconnect is timestamped with the last
+				// data received so it stays in order with the rest of the data
+				catchupTimestamp := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+				if len(msg.Timestamp) > 0 {
+					dataTime, err := parseTime(msg.Timestamp)
+					if err != nil {
+						p.log.Errorf("Parsing catchup timestamp with value '%s': %v", msg.Timestamp, err)
+					} else {
+						catchupTimestamp = dataTime
+					}
+				}
 
 				for _, fileName := range connection.OrderedFiles {
 					if fileName == connection.TeamRadioFile ||
@@ -171,9 +311,9 @@ func (p *Parser) Process() {
 								continue
 							}
 
-							p.handleMessage(fileName, abc, zeroTimestamp)
+							p.handleMessage(fileName, abc, catchupTimestamp)
 						} else {
-							p.handleMessage(fileName, fileData.(map[string]interface{}), zeroTimestamp)
+							p.handleMessage(fileName, fileData.(map[string]interface{}), catchupTimestamp)
 						}
 					}
 				}
@@ -217,12 +357,11 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 		}
 
 	case connection.SessionDataFile:
-		if p.requestedData&Event == Event {
-			outgoing, err := p.parseSessionDataData(dat, timestamp)
-			if err == nil {
-				for _, rcMsg := range outgoing {
-					p.output.AddEvent(rcMsg)
-				}
+		// Always parsed because the timing needs to know which part of qualifying it is
+		outgoing, err := p.parseSessionDataData(dat, timestamp)
+		if p.requestedData&Event == Event && err == nil {
+			for _, rcMsg := range outgoing {
+				p.output.AddEvent(rcMsg)
 			}
 		}
 
@@ -283,16 +422,15 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 		}
 
 	case connection.SessionInfoFile:
-		if p.requestedData&Event == Event {
-			outgoing, timingOutgoing, err := p.parseSessionInfoData(dat, timestamp)
-			if err == nil {
-				p.output.AddEvent(outgoing)
-			}
+		// Always parsed because the timing needs to know the session type
+		outgoing, timingOutgoing, err := p.parseSessionInfoData(dat, timestamp)
+		if p.requestedData&Event == Event && err == nil {
+			p.output.AddEvent(outgoing)
+		}
 
-			if p.requestedData&Timing == Timing {
-				for _, rcMsg := range timingOutgoing {
-					p.output.AddTiming(rcMsg)
-				}
+		if p.requestedData&Timing == Timing {
+			for _, rcMsg := range timingOutgoing {
+				p.output.AddTiming(rcMsg)
 			}
 		}
 
@@ -386,6 +524,31 @@ func (p *Parser) decompressData(data []byte) (map[string]interface{}, error) {
 	return p.deserializeData(uncompressed)
 }
 
+// Ranging over a map gives a different order every time so anything that sends a message per entry uses this to
+// always send them in the same order. Numbers are ordered by value and come before anything else.
+func orderedKeys[V any](dat map[string]V) []string {
+	keys := make([]string, 0, len(dat))
+	for key := range dat {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+
+		switch {
+		case errA == nil && errB == nil:
+			return a < b
+		case errA == nil || errB == nil:
+			return errA == nil
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+
+	return keys
+}
+
 func (p *Parser) deserializeData(data []byte) (map[string]interface{}, error) {
 	var dat map[string]interface{}
 	err := json.Unmarshal(data, &dat)
@@ -36,7 +36,9 @@ func (p *Parser) parsePositionData(dat map[string]interface{}, timestamp time.Ti
 			p.ParseTimeError(connection.PositionFile, timestamp, "Timestamp", err)
 		}
 
-		for key, entry := range record.(map[string]interface{})["Entries"].(map[string]interface{}) {
+		entries := record.(map[string]interface{})["Entries"].(map[string]interface{})
+		for _, key := range orderedKeys(entries) {
+			entry := entries[key]
 			driver, _ := strconv.ParseInt(key, 10, 8)
 			//status := entry.(map[string]interface{})["Status"].(string)
 
@@ -34,8 +34,9 @@ func (p *Parser) parseRaceControlMessagesData(dat map[string]interface{}, timest
 			p.readRaceControlMessage(msg, timestamp, &result, &eventResult, &timingResult)
 		}
 	} else if reflect.TypeOf(dat["Messages"]).Kind() == reflect.Map {
-		for _, msg := range dat["Messages"].(map[string]interface{}) {
-			p.readRaceControlMessage(msg, timestamp, &result, &eventResult, &timingResult)
+		messages := dat["Messages"].(map[string]interface{})
+		for _, msg := range orderedKeys(messages) {
+			p.readRaceControlMessage(messages[msg], timestamp, &result, &eventResult, &timingResult)
 		}
 	} else {
 		p.ParseErrorf(connection.RaceControlMessagesFile, timestamp, "Unhandled data format: %v", dat)
@@ -204,7 +205,8 @@ func (p *Parser) readRaceControlMessage(
 				p.eventState.Type == Messages.Qualifying2 ||
 				p.eventState.Type == Messages.Qualifying3 {
 
-				for x, driver := range p.driverTimes {
+				for _, x := range orderedKeys(p.driverTimes) {
+					driver := p.driverTimes[x]
 					if driver.Location == Messages.Pitlane || driver.Location == Messages.PitOut {
 						driver.ChequeredFlag = true
 						p.driverTimes[x] = driver
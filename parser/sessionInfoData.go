@@ -57,7 +57,8 @@ func (p *Parser) parseSessionInfoData(dat map[string]interface{}, timestamp time
 
 	if previousType != p.eventState.Type {
 		// Clear the chequered flag state for all cars
-		for driverNum, driverInfo := range p.driverTimes {
+		for _, driverNum := range orderedKeys(p.driverTimes) {
+			driverInfo := p.driverTimes[driverNum]
 			driverInfo.ChequeredFlag = false
 			driverInfo.Sector1 = 0
 			driverInfo.Sector2 = 0
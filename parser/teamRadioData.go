@@ -28,8 +28,9 @@ func (p *Parser) parseTeamRadioData(dat map[string]interface{}, timestamp time.T
 	result := make([]Messages.Radio, 0)
 
 	if reflect.TypeOf(dat["Captures"]).Kind() == reflect.Map {
-		for _, data := range dat["Captures"].(map[string]interface{}) {
-			p.readTeamRadio(data, timestamp, &result)
+		captures := dat["Captures"].(map[string]interface{})
+		for _, capture := range orderedKeys(captures) {
+			p.readTeamRadio(captures[capture], timestamp, &result)
 		}
 
 	} else if reflect.TypeOf(dat["Captures"]).Kind() == reflect.Slice {
@@ -26,7 +26,9 @@ func (p *Parser) parseTimingAppData(dat map[string]interface{}, timestamp time.T
 
 	result := make([]Messages.Timing, 0)
 
-	for driverStr, line := range dat["Lines"].(map[string]interface{}) {
+	lines := dat["Lines"].(map[string]interface{})
+	for _, driverStr := range orderedKeys(lines) {
+		line := lines[driverStr]
 
 		currentDriver, exists := p.driverTimes[driverStr]
 		if !exists {
@@ -57,8 +59,9 @@ func (p *Parser) parseTimingAppData(dat map[string]interface{}, timestamp time.T
 
 			switch value.(type) {
 			case map[string]interface{}:
-				for _, stintData := range value.(map[string]interface{}) {
-					p.readTimingAppData(stintData, &currentDriver, timestamp)
+				stints := value.(map[string]interface{})
+				for _, stint := range orderedKeys(stints) {
+					p.readTimingAppData(stints[stint], &currentDriver, timestamp)
 				}
 
 			case []interface{}:
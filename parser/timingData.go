@@ -39,7 +39,8 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 	var currentFastestLap time.Duration
 	var err error
 
-	for driverNumber, data := range lines.(map[string]interface{}) {
+	for _, driverNumber := range orderedKeys(lines.(map[string]interface{})) {
+		data := lines.(map[string]interface{})[driverNumber]
 		record := data.(map[string]interface{})
 
 		currentDriver, exists := p.driverTimes[driverNumber]
@@ -233,7 +234,7 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 			case []interface{}:
 
 				for key, value2 := range sectors.([]interface{}) {
-					p.processSectorTimes(string(key), value2, &currentDriver, timestamp)
+					p.processSectorTimes(strconv.Itoa(key), value2, &currentDriver, timestamp)
 				}
 
 			default:
@@ -364,8 +365,8 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 
 		orderedDrivers := make([]Messages.Timing, 0)
 
-		for _, info := range p.driverTimes {
-			orderedDrivers = append(orderedDrivers, info)
+		for _, number := range orderedKeys(p.driverTimes) {
+			orderedDrivers = append(orderedDrivers, p.driverTimes[number])
 		}
 
 		sort.SliceStable(orderedDrivers, func(i, j int) bool {
@@ -395,7 +396,8 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 	} else if fastestLapChanged && p.session == Messages.RaceSession || p.session == Messages.SprintSession {
 		// For races we need to know who has the overall fastest lap
 		result = make([]Messages.Timing, 0)
-		for x, info := range p.driverTimes {
+		for _, x := range orderedKeys(p.driverTimes) {
+			info := p.driverTimes[x]
 			info.OverallFastestLap = info.FastestLap == currentFastestLap
 			p.driverTimes[strconv.Itoa(p.driverTimes[x].Number)] = info
 			result = append(result, p.driverTimes[x])
@@ -424,7 +426,8 @@ func (p *Parser) processSectorTimes(key string, value interface{}, driver *Messa
 			}
 
 		} else if reflect.TypeOf(segments).Kind() == reflect.Map {
-			for x, info := range segments.(map[string]interface{}) {
+			for _, x := range orderedKeys(segments.(map[string]interface{})) {
+				info := segments.(map[string]interface{})[x]
 				currentSegmentIndex, _ = strconv.Atoi(x)
 				segmentState, useSegmentChange = p.calcSegment(key, info, timestamp, currentSegmentIndex, driver)
 
@@ -0,0 +1,71 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import "github.com/stephenhoran/f1gopherlib/connection"
+
+// The topics each type of data is worked out from
+var topicsFor = map[DataSource][]string{
+	Event: {
+		connection.SessionInfoFile,
+		connection.SessionDataFile,
+		connection.SessionStatusFile,
+		connection.LapCountFile,
+		connection.ExtrapolatedClockFile,
+		connection.HeartbeatFile,
+		connection.RaceControlMessagesFile,
+	},
+	RaceControl: {connection.RaceControlMessagesFile},
+	Weather:     {connection.WeatherDataFile},
+	Timing: {
+		// Pit stops are only counted once a race has started and qualifying parts come from the session data
+		connection.SessionDataFile,
+		connection.SessionStatusFile,
+		connection.TimingDataFile,
+		connection.TimingAppDataFile,
+		// DRS comes from the car data and the chequered flag for each driver from race control
+		connection.CarDataFile,
+		connection.RaceControlMessagesFile,
+	},
+	Telemetry: {connection.CarDataFile},
+	Location:  {connection.PositionFile},
+	TeamRadio: {connection.TeamRadioFile},
+	Drivers:   {connection.DriverListFile},
+}
+
+// Topics - The topics needed for the requested data, in the connection.OrderedFiles order. The driver list and
+// session info are always needed because the rest of the data is worked out using them.
+func Topics(requestedData DataSource) []string {
+	wanted := map[string]bool{
+		connection.DriverListFile:  true,
+		connection.SessionInfoFile: true,
+	}
+	for source, topics := range topicsFor {
+		if requestedData&source == source {
+			for _, topic := range topics {
+				wanted[topic] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(wanted))
+	for _, topic := range connection.OrderedFiles {
+		if wanted[topic] {
+			result = append(result, topic)
+		}
+	}
+	return result
+}
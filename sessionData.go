@@ -0,0 +1,274 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package f1gopherlib
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// SessionData - Everything that happened in a session. Each list is in the order the data was sent.
+type SessionData struct {
+	Event RaceEvent
+
+	Drivers     []Messages.Drivers
+	Timing      []Messages.Timing
+	Events      []Messages.Event
+	Telemetry   []Messages.Telemetry
+	Location    []Messages.Location
+	Weather     []Messages.Weather
+	RaceControl []Messages.RaceControlMessage
+	Radio       []Messages.Radio
+
+	// Where each drivers data is in the lists above
+	timingIndex    map[int][]int
+	telemetryIndex map[int][]int
+	locationIndex  map[int][]int
+}
+
+// Everything LoadSession reads
+const allData = parser.EventTime | parser.Event | parser.RaceControl | parser.Weather | parser.Timing |
+	parser.Telemetry | parser.Location | parser.TeamRadio | parser.Drivers
+
+// LoadSession - Replay the whole of a session as fast as possible and return everything in it. Use WithCache to
+// keep the downloaded data for next time.
+func LoadSession(event RaceEvent, opts ...Option) (*SessionData, error) {
+	f1Log.Infof("Loading session: %v", event.string())
+
+	data := createSession(event, append(opts[:len(opts):len(opts)], WithBatch()))
+	data.options.allTelemetry = true
+	data.options.stream = false
+
+	err := data.connectReplay(allData, event, data.options.cache, flowControl.StraightThrough)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.collect(event)
+}
+
+// LoadSessionFromFS - Load a whole session from the '<Topic>.jsonStream' files stored at the root of files, see
+// CreateReplayFromFS
+func LoadSessionFromFS(files fs.FS, event RaceEvent, opts ...Option) (*SessionData, error) {
+	f1Log.Infof("Loading session from files: %v", event.string())
+
+	data := createSession(event, append(opts[:len(opts):len(opts)], WithBatch()))
+	data.options.allTelemetry = true
+	data.options.stream = false
+
+	err := data.connectFsReplay(allData, files, event, flowControl.StraightThrough)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.collect(event)
+}
+
+// Reads everything until the parser has finished then closes the session
+func (f *f1gopherlib) collect(event RaceEvent) (*SessionData, error) {
+	defer f.Close()
+
+	result := &SessionData{Event: event}
+
+	for {
+		select {
+		case msg := <-f.drivers:
+			result.Drivers = append(result.Drivers, msg)
+		case msg := <-f.timing:
+			result.Timing = append(result.Timing, msg)
+		case msg := <-f.event:
+			result.Events = append(result.Events, msg)
+		case msg := <-f.telemetry:
+			result.Telemetry = append(result.Telemetry, msg)
+		case msg := <-f.location:
+			result.Location = append(result.Location, msg)
+		case msg := <-f.weather:
+			result.Weather = append(result.Weather, msg)
+		case msg := <-f.raceControlMessages:
+			result.RaceControl = append(result.RaceControl, msg)
+		case msg := <-f.radio:
+			result.Radio = append(result.Radio, msg)
+		case <-f.eventTime:
+
+		case <-f.dataHandler.Done():
+			// Nothing else will be sent so just take what is left
+			for len(f.drivers) > 0 {
+				result.Drivers = append(result.Drivers, <-f.drivers)
+			}
+			for len(f.timing) > 0 {
+				result.Timing = append(result.Timing, <-f.timing)
+			}
+			for len(f.event) > 0 {
+				result.Events = append(result.Events, <-f.event)
+			}
+			for len(f.telemetry) > 0 {
+				result.Telemetry = append(result.Telemetry, <-f.telemetry)
+			}
+			for len(f.location) > 0 {
+				result.Location = append(result.Location, <-f.location)
+			}
+			for len(f.weather) > 0 {
+				result.Weather = append(result.Weather, <-f.weather)
+			}
+			for len(f.raceControlMessages) > 0 {
+				result.RaceControl = append(result.RaceControl, <-f.raceControlMessages)
+			}
+			for len(f.radio) > 0 {
+				result.Radio = append(result.Radio, <-f.radio)
+			}
+
+			if len(result.Drivers) == 0 && len(result.Timing) == 0 && len(result.Events) == 0 {
+				return nil, errors.New("no data found for the session")
+			}
+
+			result.createIndexes()
+			return result, nil
+		}
+	}
+}
+
+func (s *SessionData) createIndexes() {
+	s.timingIndex = make(map[int][]int)
+	for x := range s.Timing {
+		s.timingIndex[s.Timing[x].Number] = append(s.timingIndex[s.Timing[x].Number], x)
+	}
+
+	s.telemetryIndex = make(map[int][]int)
+	for x := range s.Telemetry {
+		s.telemetryIndex[s.Telemetry[x].DriverNumber] = append(s.telemetryIndex[s.Telemetry[x].DriverNumber], x)
+	}
+
+	s.locationIndex = make(map[int][]int)
+	for x := range s.Location {
+		s.locationIndex[s.Location[x].DriverNumber] = append(s.locationIndex[s.Location[x].DriverNumber], x)
+	}
+}
+
+// DriverNumbers - Everyone that has timing data in number order
+func (s *SessionData) DriverNumbers() []int {
+	result := make([]int, 0, len(s.timingIndex))
+	for number := range s.timingIndex {
+		result = append(result, number)
+	}
+	sort.Ints(result)
+
+	return result
+}
+
+// TimingFor - Every timing update for the driver
+func (s *SessionData) TimingFor(driver int) []Messages.Timing {
+	result := make([]Messages.Timing, len(s.timingIndex[driver]))
+	for x, index := range s.timingIndex[driver] {
+		result[x] = s.Timing[index]
+	}
+
+	return result
+}
+
+// TelemetryFor - All of the telemetry for the driver
+func (s *SessionData) TelemetryFor(driver int) []Messages.Telemetry {
+	result := make([]Messages.Telemetry, len(s.telemetryIndex[driver]))
+	for x, index := range s.telemetryIndex[driver] {
+		result[x] = s.Telemetry[index]
+	}
+
+	return result
+}
+
+// LocationFor - Everywhere the driver's car was
+func (s *SessionData) LocationFor(driver int) []Messages.Location {
+	result := make([]Messages.Location, len(s.locationIndex[driver]))
+	for x, index := range s.locationIndex[driver] {
+		result[x] = s.Location[index]
+	}
+
+	return result
+}
+
+// TimingAt - The driver's timing as it was at the given time
+func (s *SessionData) TimingAt(driver int, at time.Time) (Messages.Timing, bool) {
+	indexes := s.timingIndex[driver]
+	found := latestAt(len(indexes), at, func(x int) time.Time { return s.Timing[indexes[x]].Timestamp })
+	if found < 0 {
+		return Messages.Timing{}, false
+	}
+
+	return s.Timing[indexes[found]], true
+}
+
+// TelemetryAt - The driver's telemetry as it was at the given time
+func (s *SessionData) TelemetryAt(driver int, at time.Time) (Messages.Telemetry, bool) {
+	indexes := s.telemetryIndex[driver]
+	found := latestAt(len(indexes), at, func(x int) time.Time { return s.Telemetry[indexes[x]].Timestamp })
+	if found < 0 {
+		return Messages.Telemetry{}, false
+	}
+
+	return s.Telemetry[indexes[found]], true
+}
+
+// LocationAt - Where the driver's car was at the given time
+func (s *SessionData) LocationAt(driver int, at time.Time) (Messages.Location, bool) {
+	indexes := s.locationIndex[driver]
+	found := latestAt(len(indexes), at, func(x int) time.Time { return s.Location[indexes[x]].Timestamp })
+	if found < 0 {
+		return Messages.Location{}, false
+	}
+
+	return s.Location[indexes[found]], true
+}
+
+// EventAt - The state of the session at the given time
+func (s *SessionData) EventAt(at time.Time) (Messages.Event, bool) {
+	found := latestAt(len(s.Events), at, func(x int) time.Time { return s.Events[x].Timestamp })
+	if found < 0 {
+		return Messages.Event{}, false
+	}
+
+	return s.Events[found], true
+}
+
+// WeatherAt - The weather at the given time
+func (s *SessionData) WeatherAt(at time.Time) (Messages.Weather, bool) {
+	found := latestAt(len(s.Weather), at, func(x int) time.Time { return s.Weather[x].Timestamp })
+	if found < 0 {
+		return Messages.Weather{}, false
+	}
+
+	return s.Weather[found], true
+}
+
+// RaceControlBetween - The race control messages sent from start up to but not including end
+func (s *SessionData) RaceControlBetween(start time.Time, end time.Time) []Messages.RaceControlMessage {
+	first := sort.Search(len(s.RaceControl), func(x int) bool { return !s.RaceControl[x].Timestamp.Before(start) })
+	last := sort.Search(len(s.RaceControl), func(x int) bool { return !s.RaceControl[x].Timestamp.Before(end) })
+	if last < first {
+		return nil
+	}
+
+	return s.RaceControl[first:last]
+}
+
+// The index of the last of count items that is at or before at, -1 if they are all after it
+func latestAt(count int, at time.Time, timestamp func(x int) time.Time) int {
+	return sort.Search(count, func(x int) bool { return timestamp(x).After(at) }) - 1
+}
@@ -0,0 +1,159 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package f1gopherlib
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+)
+
+// Handler - Called with each message a subscription matches. A returned error is logged and the messages carry on.
+type Handler func(envelope Messages.Envelope) error
+
+type subscription struct {
+	id         uint64
+	streamType Messages.StreamType
+	drivers    map[int]bool
+	handler    Handler
+}
+
+type subscriptions struct {
+	list   []subscription
+	nextId uint64
+	// Messages waiting for the handlers
+	envelopes chan Messages.Envelope
+	// Radio messages only have the driver's name
+	driverNames map[string]int
+	started     bool
+	lock        sync.Mutex
+}
+
+// Subscribe - Call handler with every message of streamType. If any drivers are given only their messages are passed
+// on, the driver list, event, weather and race control messages aren't for a driver so always are. All handlers are
+// called one after another from a single goroutine so a slow handler will hold up the rest.
+//
+// The messages wait in their own buffer for the handlers so they don't hold up the session or Stream. When handlers
+// can't keep up the SubscriptionStream backpressure policy is used, for straight through sessions too, and Dropped
+// counts what was lost. The returned function removes the subscription.
+func (f *f1gopherlib) Subscribe(streamType Messages.StreamType, drivers []int, handler Handler) (func(), error) {
+	if handler == nil {
+		return nil, errors.New("no handler given")
+	}
+
+	f.subscriptions.lock.Lock()
+	defer f.subscriptions.lock.Unlock()
+
+	f.subscriptions.nextId++
+	sub := subscription{
+		id:         f.subscriptions.nextId,
+		streamType: streamType,
+		handler:    handler,
+	}
+	if len(drivers) > 0 {
+		sub.drivers = make(map[int]bool, len(drivers))
+		for _, driver := range drivers {
+			sub.drivers[driver] = true
+		}
+	}
+	f.subscriptions.list = append(f.subscriptions.list, sub)
+
+	if !f.subscriptions.started {
+		f.subscriptions.started = true
+		f.subscriptions.driverNames = make(map[string]int)
+		f.subscriptions.envelopes = make(chan Messages.Envelope, subscriptionChannelSize)
+		f.replayTiming.SendToSubscriptions(f.subscriptions.envelopes)
+		f.wg.Add(1)
+		go f.dispatch(f.subscriptions.envelopes)
+	}
+
+	return func() { f.unsubscribe(sub.id) }, nil
+}
+
+func (f *f1gopherlib) unsubscribe(id uint64) {
+	f.subscriptions.lock.Lock()
+	defer f.subscriptions.lock.Unlock()
+
+	for x := range f.subscriptions.list {
+		if f.subscriptions.list[x].id == id {
+			f.subscriptions.list = append(f.subscriptions.list[:x], f.subscriptions.list[x+1:]...)
+			return
+		}
+	}
+}
+
+// Passes everything sent to the subscriptions to the matching handlers until the session is closed
+func (f *f1gopherlib) dispatch(envelopes <-chan Messages.Envelope) {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+
+		case envelope := <-envelopes:
+			f.subscriptions.lock.Lock()
+			if envelope.Type == Messages.TimingStream {
+				timing := envelope.Data.(Messages.Timing)
+				f.subscriptions.driverNames[timing.Name] = timing.Number
+			}
+			driver, hasDriver := f.subscriptions.driverFor(envelope)
+			// Copy so handlers can subscribe or unsubscribe without deadlocking
+			list := append([]subscription(nil), f.subscriptions.list...)
+			f.subscriptions.lock.Unlock()
+
+			for _, sub := range list {
+				if sub.streamType != envelope.Type || (hasDriver && sub.drivers != nil && !sub.drivers[driver]) {
+					continue
+				}
+
+				callHandler(sub.handler, envelope)
+			}
+		}
+	}
+}
+
+// The driver the message is about, if it is about one
+func (s *subscriptions) driverFor(envelope Messages.Envelope) (int, bool) {
+	switch data := envelope.Data.(type) {
+	case Messages.Timing:
+		return data.Number, true
+	case Messages.Telemetry:
+		return data.DriverNumber, true
+	case Messages.Location:
+		return data.DriverNumber, true
+	case Messages.Radio:
+		// Unknown names won't match any driver
+		return s.driverNames[data.Driver], true
+	default:
+		return 0, false
+	}
+}
+
+// A handler failing mustn't stop the others being called
+func callHandler(handler Handler, envelope Messages.Envelope) {
+	defer func() {
+		if r := recover(); r != nil {
+			f1Log.Errorf("Subscription handler for %s panicked: %v", envelope.Type, r)
+		}
+	}()
+
+	err := handler(envelope)
+	if err != nil {
+		f1Log.Warnf("Subscription handler for %s: %v", envelope.Type, err)
+	}
+}
@@ -0,0 +1,141 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func fixtureDriverList(t *testing.T) string {
+	data, err := os.ReadFile(filepath.Join(fixtureDir, connection.DriverListFile+".jsonStream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.TrimSpace(string(data))
+	return line[strings.Index(line, "{"):]
+}
+
+func writeArchive(t *testing.T, path string, header connection.ArchiveHeader, payloads []connection.Payload) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	headerData, _ := json.Marshal(header)
+	fmt.Fprintf(file, "F1GOPHER-ARCHIVE %d\n%s\n", connection.ArchiveVersion, headerData)
+
+	compressed := gzip.NewWriter(file)
+	encoder := json.NewEncoder(compressed)
+	for _, payload := range payloads {
+		encoder.Encode(map[string]interface{}{
+			"name":      payload.Name,
+			"data":      payload.Data,
+			"timestamp": payload.Timestamp,
+		})
+	}
+	compressed.Close()
+}
+
+func TestArchiveReplay(t *testing.T) {
+	dir := t.TempDir()
+	event := fixtureEvent()
+	drivers := connection.Payload{
+		Name:      connection.DriverListFile,
+		Data:      []byte(fixtureDriverList(t)),
+		Timestamp: "2023-03-05T15:00:00.05Z",
+	}
+
+	current := filepath.Join(dir, "current"+connection.ArchiveExtension)
+	writeArchive(t, current, connection.ArchiveHeader{
+		Country:           event.Country,
+		Name:              event.Name,
+		Session:           event.Type,
+		RaceTime:          event.RaceTime,
+		EventTime:         event.EventTime,
+		Timezone:          "Europe/London",
+		Track:             event.TrackName,
+		TrackYear:         event.TrackYearCreated,
+		TimeLostInPitlane: event.TimeLostInPitlane,
+		Url:               event.Url(),
+	}, []connection.Payload{drivers})
+
+	legacy := filepath.Join(dir, "legacy.txt")
+	err := os.WriteFile(legacy, []byte(fmt.Sprintf("%s\r\n%s\r\n%s\r\n", drivers.Name, drivers.Data, drivers.Timestamp)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := connection.ReadArchiveHeader(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != connection.ArchiveVersion || header.Name != event.Name {
+		t.Errorf("unexpected header: %+v", header)
+	}
+
+	header, err = connection.ReadArchiveHeader(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != connection.LegacyArchiveVersion {
+		t.Errorf("expected legacy archive version but got %d", header.Version)
+	}
+
+	for _, file := range []string{current, legacy} {
+		data, err := f1gopherlib.CreateDebugReplay(parser.Drivers, file, flowControl.StraightThrough)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if file == current {
+			if data.Name() != event.Name || data.Session() != Messages.RaceSession || data.Track() != event.TrackName {
+				t.Errorf("event not read from archive: %s %s %s", data.Name(), data.Session(), data.Track())
+			}
+			if data.CircuitTimezone().String() != "Europe/London" {
+				t.Errorf("unexpected timezone: %s", data.CircuitTimezone())
+			}
+			if data.TimeLostInPitlane() != event.TimeLostInPitlane {
+				t.Errorf("unexpected pitlane time: %s", data.TimeLostInPitlane())
+			}
+		}
+
+		select {
+		case msg := <-data.Drivers():
+			if len(msg.Drivers) != 2 {
+				t.Errorf("%s: expected 2 drivers but got %d", file, len(msg.Drivers))
+			}
+		case <-time.After(time.Second * 5):
+			t.Errorf("%s: timed out waiting for drivers", file)
+		}
+
+		data.Close()
+	}
+}
@@ -0,0 +1,111 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+func TestBackfill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	log := f1log.CreateLog()
+	live := &testLive{feed: make(chan connection.Payload, 10)}
+	history := connection.CreateFsReplay(ctx, wg, log, os.DirFS(fixtureDir), Messages.RaceSession, 2023)
+
+	// Joined late so the catchup has a race control message the history already has and one it doesn't
+	live.feed <- connection.Payload{
+		Name: connection.CatchupFile,
+		Data: []byte(`{"RaceControlMessages":{"Messages":[` +
+			`{"Utc":"2023-03-05T15:00:02.500","Message":"GREEN LIGHT - PIT EXIT OPEN"},` +
+			`{"Utc":"2023-03-05T17:00:00","Message":"CHEQUERED FLAG"}]}}`),
+	}
+	live.feed <- connection.Payload{Name: connection.TimingDataFile, Data: []byte(`{}`), Timestamp: "2023-03-05T15:00:01Z"}
+	live.feed <- connection.Payload{Name: connection.TimingDataFile, Data: []byte(`{}`), Timestamp: "2023-03-05T18:00:00Z"}
+
+	err, feed := connection.CreateBackfill(ctx, wg, log, live, history).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var historyEnd string
+	historySent := 0
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case payload := <-feed:
+			switch payload.Name {
+			case connection.EndOfDataFile, connection.KeyframeFile:
+				t.Fatalf("unexpected %s payload", payload.Name)
+
+			case connection.CatchupFile:
+				if historySent == 0 {
+					t.Fatal("got the live catchup before the history")
+				}
+				if payload.Timestamp != historyEnd {
+					t.Errorf("got catchup at %s, expected the end of the history at %s", payload.Timestamp, historyEnd)
+				}
+
+				var catchup struct {
+					RaceControlMessages struct {
+						Messages []struct{ Message string }
+					}
+				}
+				err = json.Unmarshal(payload.Data, &catchup)
+				if err != nil {
+					t.Fatal(err)
+				}
+				messages := catchup.RaceControlMessages.Messages
+				if len(messages) != 1 || messages[0].Message != "CHEQUERED FLAG" {
+					t.Errorf("expected only the race control message the history doesn't have but got %v", messages)
+				}
+
+			case connection.TimingDataFile:
+				if payload.Timestamp == "2023-03-05T15:00:01Z" {
+					t.Error("got live timing the history already had")
+				}
+				if payload.Timestamp == "2023-03-05T18:00:00Z" {
+					done = true
+					continue
+				}
+				fallthrough
+
+			default:
+				historySent++
+				historyEnd = payload.Timestamp
+			}
+
+		case <-timeout:
+			t.Fatal("timed out waiting for the live data after the history")
+		}
+	}
+
+	if historySent == 0 {
+		t.Error("expected the history to be sent")
+	}
+}
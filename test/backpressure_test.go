@@ -0,0 +1,347 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+)
+
+func TestBackpressure(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		policy   flowControl.Policy
+		expected []int
+		dropped  uint64
+	}{
+		{flowControl.DropNewest, []int{1}, 2},
+		{flowControl.DropOldest, []int{2}, 2},
+		{flowControl.CoalesceLatest, []int{1, 2}, 1},
+		{flowControl.Block, []int{1, 2, 2}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			wg := sync.WaitGroup{}
+
+			log := f1log.CreateLog()
+			log.SetLogOutput(io.Discard)
+
+			// Only room for one timing message
+			timing := make(chan Messages.Timing, 1)
+			eventTime := make(chan Messages.EventTime, 1000)
+
+			flow := flowControl.CreateFlowControl(
+				ctx,
+				&wg,
+				flowControl.Realtime,
+				flowControl.Config{
+					Weather:             make(chan Messages.Weather, 10),
+					RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+					Timing:              timing,
+					Event:               make(chan Messages.Event, 10),
+					Telemetry:           make(chan Messages.Telemetry, 10),
+					Location:            make(chan Messages.Location, 10),
+					EventTime:           eventTime,
+					Radio:               make(chan Messages.Radio, 10),
+					Drivers:             make(chan Messages.Drivers, 10),
+					Backpressure:        flowControl.Backpressure{Messages.TimingStream: test.policy},
+				},
+				log)
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+
+			flow.SetSpeed(10)
+			flow.AddEvent(Messages.Event{Timestamp: start})
+			flow.AddTiming(Messages.Timing{Timestamp: start, Number: 1})
+			flow.AddTiming(Messages.Timing{Timestamp: start, Number: 2})
+			flow.AddTiming(Messages.Timing{Timestamp: start, Number: 2, Position: 1})
+			go flow.Run()
+
+			// Blocking waits for the timing to be read
+			if test.policy != flowControl.Block {
+				// Wait for the timing to be sent and anything held back to be tried again
+				for count := 0; count < 10; count++ {
+					<-eventTime
+				}
+			}
+
+			var received []int
+			done := time.After(time.Second)
+			for reading := true; reading; {
+				select {
+				case msg := <-timing:
+					received = append(received, msg.Number)
+				case <-eventTime:
+				case <-done:
+					reading = false
+				}
+			}
+
+			if len(received) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, received)
+			}
+			for x := range received {
+				if received[x] != test.expected[x] {
+					t.Fatalf("expected %v, got %v", test.expected, received)
+				}
+			}
+
+			if dropped := flow.Dropped()[Messages.TimingStream]; dropped != test.dropped {
+				t.Errorf("expected %d dropped, got %d", test.dropped, dropped)
+			}
+		})
+	}
+}
+
+func TestTimingDeltaBackpressure(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	log := f1log.CreateLog()
+	log.SetLogOutput(io.Discard)
+
+	// Only room for one delta
+	deltas := make(chan Messages.TimingDelta, 1)
+	eventTime := make(chan Messages.EventTime, 1000)
+
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              make(chan Messages.Timing, 10),
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           make(chan Messages.Telemetry, 10),
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			TimingDeltas:        deltas,
+		},
+		log)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	flow.SetSpeed(10)
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	flow.AddTiming(Messages.Timing{Timestamp: start, Number: 1, Position: 2})
+	flow.AddTiming(Messages.Timing{Timestamp: start, Number: 2, Position: 1})
+	go flow.Run()
+
+	for count := 0; count < 4; count++ {
+		<-eventTime
+	}
+
+	if dropped := flow.Dropped()[Messages.TimingDeltaStream]; dropped != 1 {
+		t.Errorf("expected 1 delta dropped, got %d", dropped)
+	}
+
+	// The first delta was sent, the other driver's changes come with their next delta
+	delta := <-deltas
+	if delta.Number != 1 {
+		t.Errorf("expected the delta for 1, got %d", delta.Number)
+	}
+}
+
+func TestTimingDeltaDropPolicies(t *testing.T) {
+	for _, policy := range []flowControl.Policy{flowControl.DropOldest, flowControl.CoalesceLatest} {
+		t.Run(policy.String(), func(t *testing.T) {
+			checkTimingDeltaPolicy(t, policy)
+		})
+	}
+}
+
+// Drops deltas while nothing is reading them and checks the deltas read still rebuild the latest timing
+func checkTimingDeltaPolicy(t *testing.T, policy flowControl.Policy) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	log := f1log.CreateLog()
+	log.SetLogOutput(io.Discard)
+
+	// Only room for one delta
+	deltas := make(chan Messages.TimingDelta, 1)
+	eventTime := make(chan Messages.EventTime, 1000)
+
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              make(chan Messages.Timing, 10),
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           make(chan Messages.Telemetry, 10),
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			TimingDeltas:        deltas,
+			Backpressure:        flowControl.Backpressure{Messages.TimingDeltaStream: policy},
+		},
+		log)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	waitUntil := func(target time.Time) {
+		t.Helper()
+
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case current := <-eventTime:
+				if !current.Timestamp.Before(target) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %s", target)
+			}
+		}
+	}
+
+	var rebuilt Messages.Timing
+	apply := func() {
+		t.Helper()
+
+		for len(deltas) > 0 {
+			delta := <-deltas
+			if err := delta.Apply(&rebuilt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	flow.SetSpeed(10)
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	flow.AddTiming(Messages.Timing{Timestamp: start, Number: 1, Position: 2})
+	// The pit stop is only in the delta that gets thrown away
+	flow.AddTiming(Messages.Timing{Timestamp: start.Add(time.Second), Number: 1, Position: 3, Pitstops: 1})
+	flow.AddTiming(Messages.Timing{Timestamp: start.Add(2 * time.Second), Number: 1, Position: 4, Pitstops: 1, Lap: 2})
+	go flow.Run()
+
+	waitUntil(start.Add(3 * time.Second))
+	apply()
+
+	latest := Messages.Timing{Timestamp: start.Add(4 * time.Second), Number: 1, Position: 1, Pitstops: 1, Lap: 2}
+	flow.AddTiming(latest)
+	waitUntil(start.Add(5 * time.Second))
+	apply()
+
+	if !reflect.DeepEqual(rebuilt, latest) {
+		t.Errorf("rebuilt timing doesn't match:\nexpected %+v\ngot      %+v", latest, rebuilt)
+	}
+}
+
+func TestSubscriptionBackpressure(t *testing.T) {
+	for _, flowType := range []flowControl.FlowType{flowControl.Realtime, flowControl.StraightThrough} {
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := sync.WaitGroup{}
+
+		log := f1log.CreateLog()
+		log.SetLogOutput(io.Discard)
+
+		start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+		weather := make(chan Messages.Weather, 10)
+		eventTime := make(chan Messages.EventTime, 1000)
+
+		// Only room for one message in the stream and for the subscriptions, neither are read
+		stream := make(chan Messages.Envelope, 1)
+		subscriptions := make(chan Messages.Envelope, 1)
+
+		flow := flowControl.CreateFlowControl(
+			ctx,
+			&wg,
+			flowType,
+			flowControl.Config{
+				Weather:             weather,
+				RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+				Timing:              make(chan Messages.Timing, 10),
+				Event:               make(chan Messages.Event, 10),
+				Telemetry:           make(chan Messages.Telemetry, 10),
+				Location:            make(chan Messages.Location, 10),
+				EventTime:           eventTime,
+				Radio:               make(chan Messages.Radio, 10),
+				Drivers:             make(chan Messages.Drivers, 10),
+				Stream:              stream,
+			},
+			log)
+		flow.SetSpeed(10)
+		flow.SendToSubscriptions(subscriptions)
+		go flow.Run()
+
+		if flowType == flowControl.StraightThrough {
+			// The stream waits to be read
+			go func() {
+				for {
+					select {
+					case <-stream:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		flow.AddEvent(Messages.Event{Timestamp: start})
+		for x := 0; x < 3; x++ {
+			flow.AddWeather(Messages.Weather{Timestamp: start})
+		}
+
+		timeout := time.After(5 * time.Second)
+		for received := 0; received < 3; {
+			select {
+			case <-weather:
+				received++
+			case <-eventTime:
+			case <-timeout:
+				t.Fatalf("%v: the subscriptions not being read held up the weather", flowType)
+			}
+		}
+
+		dropped := flow.Dropped()
+		if dropped[Messages.SubscriptionStream] == 0 {
+			t.Errorf("%v: expected subscription messages to be dropped", flowType)
+		}
+		if flowType == flowControl.Realtime && dropped[Messages.EnvelopeStream] == 0 {
+			t.Error("expected stream messages to be dropped")
+		}
+
+		cancel()
+		wg.Wait()
+	}
+}
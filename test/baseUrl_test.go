@@ -0,0 +1,82 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+type countingTransport struct {
+	requests int32
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestReplayFromBaseUrl(t *testing.T) {
+	server, served := fixtureServer(t)
+	transport := &countingTransport{}
+
+	data, err := f1gopherlib.CreateReplay(
+		parser.Drivers|parser.TeamRadio,
+		fixtureEvent(),
+		t.TempDir(),
+		flowControl.StraightThrough,
+		f1gopherlib.WithBaseUrl(server.URL+"/static/"),
+		f1gopherlib.WithHttpClient(&http.Client{Transport: transport, Timeout: time.Second * 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	timeout := time.After(time.Second * 15)
+
+	select {
+	case drivers := <-data.Drivers():
+		if len(drivers.Drivers) != 2 {
+			t.Errorf("expected 2 drivers but got %d", len(drivers.Drivers))
+		}
+	case <-timeout:
+		t.Fatal("timed out waiting for the driver list")
+	}
+
+	select {
+	case radio := <-data.Radio():
+		if string(radio.Msg) != "fake radio audio\n" {
+			t.Errorf("unexpected team radio content: %q", radio.Msg)
+		}
+	case <-timeout:
+		t.Fatal("timed out waiting for team radio")
+	}
+
+	if atomic.LoadInt32(served) == 0 {
+		t.Error("no requests were made to the base url")
+	}
+
+	if atomic.LoadInt32(&transport.requests) != atomic.LoadInt32(served) {
+		t.Errorf("expected all %d requests to use the provided client but only %d did",
+			atomic.LoadInt32(served), atomic.LoadInt32(&transport.requests))
+	}
+}
@@ -0,0 +1,102 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// Reads the whole fixture session as a batch, returning the events and timing in the order received
+func readBatch(t *testing.T) ([]Messages.Event, []Messages.Timing) {
+	t.Helper()
+
+	// Realtime is ignored for batches
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Event|parser.Timing|parser.Drivers,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime,
+		f1gopherlib.WithBatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	if err = data.SeekTo(time.Date(2023, 3, 5, 15, 0, 3, 0, time.UTC)); err == nil {
+		t.Error("batch replays shouldn't seek")
+	}
+
+	var events []Messages.Event
+	var timing []Messages.Timing
+	timeout := time.After(time.Second)
+
+	// Carry on until nothing has been sent for a while after the end of the session
+	var idle <-chan time.Time
+	for reading := true; reading; {
+		select {
+		case event := <-data.Event():
+			events = append(events, event)
+			if event.Status == Messages.Finished {
+				idle = time.After(100 * time.Millisecond)
+			}
+
+		case msg := <-data.Timing():
+			timing = append(timing, msg)
+
+		case <-data.Drivers():
+		case <-data.Time():
+		case <-idle:
+			reading = false
+		case <-timeout:
+			t.Fatal("timed out reading the session")
+		}
+	}
+
+	return events, timing
+}
+
+func TestBatchReplay(t *testing.T) {
+	events, timing := readBatch(t)
+
+	for x := 1; x < len(events); x++ {
+		if events[x].Timestamp.Before(events[x-1].Timestamp) {
+			t.Errorf("event at %s was sent after %s", events[x].Timestamp, events[x-1].Timestamp)
+		}
+	}
+
+	for x := 1; x < len(timing); x++ {
+		if timing[x].Timestamp.Before(timing[x-1].Timestamp) {
+			t.Errorf("timing at %s was sent after %s", timing[x].Timestamp, timing[x-1].Timestamp)
+		}
+	}
+
+	if len(timing) == 0 {
+		t.Error("no timing was sent")
+	}
+
+	// The same data is always sent in the same order
+	againEvents, againTiming := readBatch(t)
+	if !reflect.DeepEqual(events, againEvents) || !reflect.DeepEqual(timing, againTiming) {
+		t.Error("reading the session again gave different results")
+	}
+}
@@ -0,0 +1,91 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+)
+
+func TestCoalescing(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	timing := make(chan Messages.Timing, 100)
+	event := make(chan Messages.Event, 100)
+	eventTime := make(chan Messages.EventTime, 1000)
+
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              timing,
+			Event:               event,
+			Telemetry:           make(chan Messages.Telemetry, 10),
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			Coalesce:            true,
+		},
+		f1log.CreateLog())
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	flow.SetSpeed(10)
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	flow.AddEvent(Messages.Event{Timestamp: start, CurrentLap: 1})
+	for lap := 1; lap <= 3; lap++ {
+		flow.AddTiming(Messages.Timing{Timestamp: start, Number: 1, Lap: lap})
+		flow.AddTiming(Messages.Timing{Timestamp: start, Number: 44, Lap: lap})
+	}
+	// Not due until a later tick
+	flow.AddTiming(Messages.Timing{Timestamp: start.Add(time.Minute), Number: 1, Lap: 4})
+	go flow.Run()
+
+	for count := 0; count < 10; count++ {
+		<-eventTime
+	}
+
+	if len(event) != 1 {
+		t.Fatalf("expected one event, got %d", len(event))
+	}
+	if msg := <-event; msg.CurrentLap != 1 {
+		t.Errorf("expected the latest event, got lap %d", msg.CurrentLap)
+	}
+
+	if len(timing) != 2 {
+		t.Fatalf("expected timing for two drivers, got %d", len(timing))
+	}
+	for _, number := range []int{1, 44} {
+		if msg := <-timing; msg.Number != number || msg.Lap != 3 {
+			t.Errorf("expected the latest timing for %d, got %d on lap %d", number, msg.Number, msg.Lap)
+		}
+	}
+}
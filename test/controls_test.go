@@ -0,0 +1,210 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// Reads everything from the session until stop is closed
+func drain(data f1gopherlib.F1GopherLib, stop <-chan struct{}) {
+	for {
+		select {
+		case <-data.Time():
+		case <-data.Timing():
+		case <-data.Event():
+		case <-data.Drivers():
+		case <-data.RaceControlMessages():
+		case <-stop:
+			return
+		}
+	}
+}
+
+func TestPause(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	// Wait for the time to start
+	select {
+	case <-data.Time():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the time")
+	}
+
+	// Pausing is done by the time it returns
+	data.TogglePause()
+	if !data.IsPaused() {
+		t.Fatal("expected the session to be paused")
+	}
+
+	for len(data.Time()) > 0 {
+		<-data.Time()
+	}
+	time.Sleep(300 * time.Millisecond)
+	if len(data.Time()) != 0 {
+		t.Error("the time moved on while paused")
+	}
+
+	data.TogglePause()
+	if data.IsPaused() {
+		t.Fatal("expected the session to be playing")
+	}
+
+	select {
+	case <-data.Time():
+	case <-time.After(time.Second):
+		t.Error("the time didn't move on after playing again")
+	}
+}
+
+// Run with -race to check the controls can be used from any goroutine while the session plays
+func TestConcurrentControls(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime|parser.RaceControl,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	stop := make(chan struct{})
+	readers := sync.WaitGroup{}
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		drain(data, stop)
+	}()
+
+	controls := sync.WaitGroup{}
+	for x := 0; x < 4; x++ {
+		controls.Add(1)
+		go func() {
+			defer controls.Done()
+
+			for y := 0; y < 20; y++ {
+				data.TogglePause()
+				data.IsPaused()
+				data.IncrementTime(100 * time.Millisecond)
+				data.IncrementLap()
+				data.SkipToSessionStart()
+				data.SetSpeed(float64(10 + y))
+				data.Speed()
+				data.Snapshot()
+				data.Dropped()
+			}
+		}()
+	}
+
+	controls.Wait()
+	close(stop)
+	readers.Wait()
+}
+
+func TestControlsWhileBlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+	eventTime := make(chan Messages.EventTime, 1000)
+	// Only room for one message and it waits to be read
+	stream := make(chan Messages.Envelope, 1)
+
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              make(chan Messages.Timing, 10),
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           make(chan Messages.Telemetry, 10),
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			Stream:              stream,
+			Backpressure:        flowControl.Backpressure{Messages.EnvelopeStream: flowControl.Block},
+		},
+		f1log.CreateLog())
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	flow.SetSpeed(10)
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	for x := 0; x < 3; x++ {
+		flow.AddWeather(Messages.Weather{Timestamp: start})
+	}
+	go flow.Run()
+
+	// Wait for the stream to fill up so sending the rest is blocked
+	timeout := time.After(5 * time.Second)
+	for len(stream) < cap(stream) {
+		select {
+		case <-timeout:
+			t.Fatal("timed out waiting for the stream to fill up")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// The reader uses the controls before it carries on reading
+	done := make(chan bool)
+	go func() {
+		flow.TogglePause()
+		done <- flow.IsPaused()
+	}()
+
+	select {
+	case paused := <-done:
+		if !paused {
+			t.Error("expected the flow to be paused")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the controls were blocked while waiting for the stream to be read")
+	}
+
+	// Everything still arrives after being read
+	for x := 0; x < 4; x++ {
+		select {
+		case <-stream:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out reading the stream")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+)
+
+func createTestFlow(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	flowType flowControl.FlowType,
+	timing chan Messages.Timing,
+	eventTime chan Messages.EventTime) flowControl.Flow {
+
+	return flowControl.CreateFlowControl(
+		ctx,
+		wg,
+		flowType,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              timing,
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           make(chan Messages.Telemetry, 10),
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+		},
+		f1log.CreateLog())
+}
+
+func TestDelayStraightThrough(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	timing := make(chan Messages.Timing, 10)
+	flow := createTestFlow(ctx, &wg, flowControl.StraightThrough, timing, make(chan Messages.EventTime, 10))
+
+	flow.IncrementDelay(time.Minute)
+	flow.IncrementDelay(time.Minute)
+	flow.AddTiming(Messages.Timing{Number: 1})
+	flow.AddTiming(Messages.Timing{Number: 2})
+
+	select {
+	case <-timing:
+		t.Fatal("timing was sent before the delay")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Catching up sends everything that has now been held back for long enough, in order
+	flow.DecrementDelay(5 * time.Minute)
+	if flow.Delay() != 0 {
+		t.Errorf("expected no delay, got %s", flow.Delay())
+	}
+
+	for _, number := range []int{1, 2} {
+		select {
+		case msg := <-timing:
+			if msg.Number != number {
+				t.Errorf("expected timing for %d, got %d", number, msg.Number)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the timing")
+		}
+	}
+}
+
+func TestDelayRealtime(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	eventTime := make(chan Messages.EventTime, 100)
+	flow := createTestFlow(ctx, &wg, flowControl.Realtime, make(chan Messages.Timing, 10), eventTime)
+	flow.SetSpeed(10)
+	go flow.Run()
+
+	flow.IncrementDelay(30 * time.Second)
+	if flow.Delay() != 30*time.Second {
+		t.Errorf("expected a 30s delay, got %s", flow.Delay())
+	}
+
+	flow.AddEvent(Messages.Event{Timestamp: start})
+
+	// The time is held back by the delay
+	select {
+	case msg := <-eventTime:
+		if !msg.Timestamp.Equal(start.Add(-30 * time.Second)) {
+			t.Errorf("expected the time to start 30s behind, got %s", msg.Timestamp.Sub(start))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the time")
+	}
+}
@@ -0,0 +1,252 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/fakeLive"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// Passes on whatever the test sends as if it came from live
+type testLive struct {
+	feed chan connection.Payload
+}
+
+func (l *testLive) Connect() (error, <-chan connection.Payload) { return nil, l.feed }
+func (l *testLive) IncrementTime(amount time.Duration)          {}
+func (l *testLive) JumpToStart() time.Time                      { return time.Time{} }
+func (l *testLive) SetSpeed(speed float64)                      {}
+func (l *testLive) SeekTo(target time.Time, from time.Time) error {
+	return nil
+}
+func (l *testLive) LapStartTime(lap int) (time.Time, error) { return time.Time{}, nil }
+func (l *testLive) TimeWhenRemaining(remaining time.Duration) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func expectPayload(t *testing.T, feed <-chan connection.Payload, name string, timestamp string) {
+	t.Helper()
+
+	select {
+	case payload := <-feed:
+		if payload.Name != name || payload.Timestamp != timestamp {
+			t.Fatalf("got payload %s at %s, expected %s at %s", payload.Name, payload.Timestamp, name, timestamp)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for payload %s", name)
+	}
+}
+
+func TestDvrRewind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	dir := t.TempDir()
+
+	live := &testLive{feed: make(chan connection.Payload, 10)}
+	dvr := connection.CreateDvr(ctx, wg, f1log.CreateLog(), live, dir)
+
+	err, feed := dvr.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live.feed <- connection.Payload{Name: connection.LapCountFile, Data: []byte(`{"CurrentLap":1}`), Timestamp: "2023-03-05T15:00:00Z"}
+	live.feed <- connection.Payload{Name: connection.TimingDataFile, Data: []byte(`{}`), Timestamp: "2023-03-05T15:00:01Z"}
+	live.feed <- connection.Payload{Name: connection.LapCountFile, Data: []byte(`{"CurrentLap":2}`), Timestamp: "2023-03-05T15:01:30Z"}
+
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:00:00Z")
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:00:00Z")
+	expectPayload(t, feed, connection.TimingDataFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:01:30Z")
+
+	latest := time.Date(2023, 3, 5, 15, 1, 30, 0, time.UTC)
+	if !dvr.Latest().Equal(latest) {
+		t.Errorf("got latest %s, expected %s", dvr.Latest(), latest)
+	}
+
+	lapStart, err := dvr.LapStartTime(2)
+	if err != nil || !lapStart.Equal(latest) {
+		t.Errorf("got lap 2 start %s (%v), expected %s", lapStart, err, latest)
+	}
+
+	// Everything is sent again from the beginning
+	err = dvr.SeekTo(time.Date(2023, 3, 5, 15, 0, 1, 0, time.UTC), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectPayload(t, feed, connection.SeekFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:00:00Z")
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:00:00Z")
+	expectPayload(t, feed, connection.TimingDataFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:01:30Z")
+
+	// Followed by anything new from live
+	live.feed <- connection.Payload{Name: connection.TimingDataFile, Data: []byte(`{}`), Timestamp: "2023-03-05T15:01:31Z"}
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:01:30Z")
+	expectPayload(t, feed, connection.TimingDataFile, "2023-03-05T15:01:31Z")
+
+	cancel()
+	wg.Wait()
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("expected the buffer file to be removed but found %d files", len(files))
+	}
+}
+
+func TestDvrSeekFromKeyframe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	live := &testLive{feed: make(chan connection.Payload, 10)}
+	dvr := connection.CreateDvr(ctx, wg, f1log.CreateLog(), live, t.TempDir())
+
+	err, feed := dvr.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live.feed <- connection.Payload{Name: connection.LapCountFile, Data: []byte(`{"CurrentLap":1}`), Timestamp: "2023-03-05T15:00:00Z"}
+	live.feed <- connection.Payload{Name: connection.TimingDataFile, Data: []byte(`{}`), Timestamp: "2023-03-05T15:00:01Z"}
+	live.feed <- connection.Payload{Name: connection.LapCountFile, Data: []byte(`{"CurrentLap":2}`), Timestamp: "2023-03-05T15:01:30Z"}
+
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:00:00Z")
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:00:00Z")
+	expectPayload(t, feed, connection.TimingDataFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.KeyframeFile, "2023-03-05T15:00:01Z")
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:01:30Z")
+
+	// Only the data after the keyframe is sent again
+	err = dvr.SeekTo(time.Date(2023, 3, 5, 15, 0, 30, 0, time.UTC), time.Date(2023, 3, 5, 15, 0, 1, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case payload := <-feed:
+		if payload.Name != connection.SeekFile || string(payload.Data) != "2023-03-05T15:00:01Z" {
+			t.Fatalf("got payload %s with '%s', expected a seek from the keyframe", payload.Name, payload.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the seek")
+	}
+
+	expectPayload(t, feed, connection.LapCountFile, "2023-03-05T15:01:30Z")
+}
+
+func TestDvrTimeWhenRemaining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	live := &testLive{feed: make(chan connection.Payload, 10)}
+	dvr := connection.CreateDvr(ctx, wg, f1log.CreateLog(), live, t.TempDir())
+
+	err, feed := dvr.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The clock is stopped in the catchup and started live, which only sends what changed
+	live.feed <- connection.Payload{
+		Name: connection.CatchupFile,
+		Data: []byte(`{"ExtrapolatedClock":{"Utc":"2023-03-05T14:55:00Z","Remaining":"01:00:00","Extrapolating":false}}`),
+	}
+	live.feed <- connection.Payload{
+		Name:      connection.ExtrapolatedClockFile,
+		Data:      []byte(`{"Utc":"2023-03-05T15:00:00Z","Extrapolating":true}`),
+		Timestamp: "2023-03-05T15:00:00Z",
+	}
+
+	expectPayload(t, feed, connection.CatchupFile, "")
+	expectPayload(t, feed, connection.ExtrapolatedClockFile, "2023-03-05T15:00:00Z")
+
+	remaining, err := dvr.TimeWhenRemaining(50 * time.Minute)
+	expected := time.Date(2023, 3, 5, 15, 10, 0, 0, time.UTC)
+	if err != nil || !remaining.Equal(expected) {
+		t.Errorf("got 50 minutes remaining at %s (%v), expected %s", remaining, err, expected)
+	}
+
+	remaining, err = dvr.TimeWhenRemaining(time.Hour)
+	expected = time.Date(2023, 3, 5, 14, 55, 0, 0, time.UTC)
+	if err != nil || !remaining.Equal(expected) {
+		t.Errorf("got an hour remaining at %s (%v), expected %s", remaining, err, expected)
+	}
+}
+
+func TestDvrCatchUpToLive(t *testing.T) {
+	assets, _ := fixtureServer(t)
+
+	server, err := fakeLive.CreateFromDir(fixtureDir, 1, f1log.CreateLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := httptest.NewServer(server)
+	t.Cleanup(live.Close)
+
+	data, err := f1gopherlib.CreateLiveRealtime(
+		parser.EventTime|parser.Event|parser.Drivers|parser.Timing,
+		f1gopherlib.WithLiveEvent(fixtureEvent()),
+		f1gopherlib.WithLiveUrl(live.URL+"/signalr"),
+		f1gopherlib.WithBaseUrl(assets.URL+"/static/"),
+		f1gopherlib.WithDvr(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	expectStates(t, data.ConnectionStatus(), Messages.Connecting, Messages.Connected)
+	expectDrivers(t, data)
+
+	// Live carries on while paused so playback falls behind
+	data.TogglePause()
+	time.Sleep(3 * time.Second)
+	behind := data.BehindLive()
+	if behind <= 0 {
+		t.Fatalf("expected to be behind live after pausing but got %s", behind)
+	}
+
+	err = data.CatchUpToLive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.IsPaused() {
+		t.Error("expected catching up to live to carry on playing")
+	}
+
+	time.Sleep(2 * time.Second)
+	if data.BehindLive() >= behind {
+		t.Errorf("expected to be less than %s behind live after catching up but was %s", behind, data.BehindLive())
+	}
+}
@@ -0,0 +1,195 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/fakeLive"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func expectStates(t *testing.T, status <-chan Messages.ConnectionStatus, states ...Messages.ConnectionState) {
+	t.Helper()
+
+	for _, want := range states {
+		select {
+		case got := <-status:
+			if got.State != want {
+				t.Fatalf("got connection state %s (%s), expected %s", got.State, got.Error, want)
+			}
+
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for connection state %s", want)
+		}
+	}
+}
+
+func expectDrivers(t *testing.T, data f1gopherlib.F1GopherLib) {
+	t.Helper()
+
+	select {
+	case drivers := <-data.Drivers():
+		if len(drivers.Drivers) != 2 {
+			t.Errorf("expected 2 drivers but got %d", len(drivers.Drivers))
+		}
+
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the driver list")
+	}
+}
+
+func TestLiveFromFakeServer(t *testing.T) {
+	assets, _ := fixtureServer(t)
+
+	server, err := fakeLive.CreateFromDir(fixtureDir, 4, f1log.CreateLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := httptest.NewServer(server)
+	t.Cleanup(live.Close)
+
+	event := fixtureEvent()
+	recording := t.TempDir()
+
+	data, err := f1gopherlib.CreateLiveRealtime(
+		parser.Drivers|parser.Timing|parser.RaceControl|parser.Weather,
+		f1gopherlib.WithLiveEvent(event),
+		f1gopherlib.WithLiveUrl(live.URL+"/signalr"),
+		f1gopherlib.WithBaseUrl(assets.URL+"/static/"),
+		f1gopherlib.WithRecording(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, data.ConnectionStatus(), Messages.Connecting, Messages.Connected)
+	expectDrivers(t, data)
+
+	select {
+	case msg := <-data.RaceControlMessages():
+		if msg.Msg != "GREEN LIGHT - PIT EXIT OPEN" {
+			t.Errorf("unexpected race control message: %s", msg.Msg)
+		}
+
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a race control message")
+	}
+
+	// Wait for the change of lead
+	timeout := time.After(10 * time.Second)
+	for leader := 0; leader != 44; {
+		select {
+		case timing := <-data.Timing():
+			if timing.Position == 1 {
+				leader = timing.Number
+			}
+
+		case <-timeout:
+			t.Fatal("timed out waiting for the change of lead")
+		}
+	}
+
+	for len(data.Timing()) > 0 {
+		<-data.Timing()
+	}
+
+	// The catchup after reconnecting rebuilds the timing
+	server.DropConnections()
+	expectStates(t, data.ConnectionStatus(), Messages.Reconnecting, Messages.Connected)
+
+	select {
+	case <-data.Timing():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for timing after reconnecting")
+	}
+
+	// Let the rest of the session play out so the team radio gets recorded
+	time.Sleep(server.Duration())
+	data.Close()
+
+	recorded := filepath.Join(recording, "2023", "2023-03-05_Test Grand Prix", "Race")
+	for _, name := range []string{"DriverList.jsonStream", "TimingData.jsonStream", "CarData.z.jsonStream"} {
+		if _, err = os.Stat(filepath.Join(recorded, name)); err != nil {
+			t.Errorf("missing recorded file: %v", err)
+		}
+	}
+
+	replay, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.TeamRadio,
+		recorded,
+		event,
+		flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	expectDrivers(t, replay)
+
+	select {
+	case radio := <-replay.Radio():
+		if string(radio.Msg) != "fake radio audio\n" {
+			t.Errorf("unexpected team radio content: %q", radio.Msg)
+		}
+
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for recorded team radio")
+	}
+}
+
+func TestLiveReconnectDoesNotRepeatRaceControl(t *testing.T) {
+	server, err := fakeLive.CreateFromDir(fixtureDir, 4, f1log.CreateLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := httptest.NewServer(server)
+	t.Cleanup(live.Close)
+
+	data, err := f1gopherlib.CreateLiveRealtime(
+		parser.RaceControl,
+		f1gopherlib.WithLiveEvent(fixtureEvent()),
+		f1gopherlib.WithLiveUrl(live.URL+"/signalr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	expectStates(t, data.ConnectionStatus(), Messages.Connecting, Messages.Connected)
+
+	select {
+	case <-data.RaceControlMessages():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a race control message")
+	}
+
+	// The catchup after reconnecting still has the message in it
+	server.DropConnections()
+	expectStates(t, data.ConnectionStatus(), Messages.Reconnecting, Messages.Connected)
+
+	select {
+	case msg := <-data.RaceControlMessages():
+		t.Errorf("race control message sent again after reconnecting: %s", msg.Msg)
+	case <-time.After(2 * time.Second):
+	}
+}
@@ -0,0 +1,74 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+)
+
+// A short made up race stored in the same layout as the live timing static files
+const fixtureDir = "testdata/session"
+
+func fixtureEvent() f1gopherlib.RaceEvent {
+	raceTime := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+
+	return *f1gopherlib.CreateRaceEvent(
+		"Testland",
+		raceTime,
+		raceTime,
+		Messages.RaceSession,
+		"Test Grand Prix",
+		"Test Circuit",
+		2023,
+		time.Second*20,
+		"Test",
+		"UTC")
+}
+
+// Serves the fixture session under the same paths as the live timing server and counts the requests made
+func fixtureServer(t *testing.T) (*httptest.Server, *int32) {
+	var requests int32
+	event := fixtureEvent()
+	eventPath := strings.TrimPrefix(event.Url(), f1gopherlib.DefaultBaseUrl)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		name := strings.TrimPrefix(r.URL.Path, "/static/"+eventPath)
+		data, err := os.ReadFile(filepath.Join(fixtureDir, filepath.FromSlash(name)))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(connection.NotFoundResponse))
+			return
+		}
+
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
@@ -0,0 +1,159 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func zipFixture(t *testing.T) fs.FS {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	err := fs.WalkDir(os.DirFS(fixtureDir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := os.ReadFile(fixtureDir + "/" + path)
+		if err != nil {
+			return err
+		}
+
+		f, err := w.Create(path)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReplayFromFS(t *testing.T) {
+	sources := map[string]func() (f1gopherlib.F1GopherLib, error){
+		"directory": func() (f1gopherlib.F1GopherLib, error) {
+			return f1gopherlib.CreateReplayFromDir(
+				parser.Drivers|parser.TeamRadio|parser.Location,
+				fixtureDir,
+				fixtureEvent(),
+				flowControl.StraightThrough)
+		},
+		"zip": func() (f1gopherlib.F1GopherLib, error) {
+			return f1gopherlib.CreateReplayFromFS(
+				parser.Drivers|parser.TeamRadio|parser.Location,
+				zipFixture(t),
+				fixtureEvent(),
+				flowControl.StraightThrough)
+		},
+	}
+
+	for name, create := range sources {
+		t.Run(name, func(t *testing.T) {
+			data, err := create()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer data.Close()
+
+			if data.Name() != "Test Grand Prix" {
+				t.Errorf("unexpected session name: %s", data.Name())
+			}
+
+			timeout := time.After(time.Second * 10)
+			locations := 0
+
+			for {
+				select {
+				case <-data.Location():
+					locations++
+					continue
+
+				case radio := <-data.Radio():
+					if string(radio.Msg) != "fake radio audio\n" {
+						t.Errorf("unexpected team radio content: %q", radio.Msg)
+					}
+
+				case <-timeout:
+					t.Fatal("timed out waiting for team radio")
+				}
+				break
+			}
+
+			if locations == 0 {
+				t.Error("expected location data from the compressed position file")
+			}
+		})
+	}
+}
+
+func TestReplayFromMissingDir(t *testing.T) {
+	_, err := f1gopherlib.CreateReplayFromDir(parser.Drivers, "testdata/does-not-exist", fixtureEvent(), flowControl.StraightThrough)
+	if err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestReplayFromFSWithoutDriverList(t *testing.T) {
+	files := fstest.MapFS{}
+	err := fs.WalkDir(os.DirFS(fixtureDir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path == "DriverList.jsonStream" {
+			return err
+		}
+
+		data, err := os.ReadFile(fixtureDir + "/" + path)
+		files[path] = &fstest.MapFile{Data: data}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := f1gopherlib.CreateReplayFromFS(parser.RaceControl, files, fixtureEvent(), flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	select {
+	case msg := <-data.RaceControlMessages():
+		if msg.Msg != "GREEN LIGHT - PIT EXIT OPEN" {
+			t.Errorf("unexpected race control message: %s", msg.Msg)
+		}
+
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a race control message")
+	}
+}
@@ -0,0 +1,116 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func TestKeyframes(t *testing.T) {
+	requested := parser.Drivers | parser.Timing | parser.Event
+	keyframeFile := filepath.Join(t.TempDir(), "Keyframes.json")
+
+	data, err := f1gopherlib.CreateReplayFromDir(
+		requested,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough,
+		f1gopherlib.WithKeyframeInterval(time.Second),
+		f1gopherlib.WithKeyframeFile(keyframeFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.SetSpeed(10)
+
+	// Play the session to build the keyframes
+	end := time.Date(2023, 3, 5, 15, 0, 5, 0, time.UTC)
+	timeout := time.After(10 * time.Second)
+	for playing := true; playing; {
+		select {
+		case eventTime := <-data.Time():
+			playing = eventTime.Timestamp.Before(end)
+
+		case <-data.Timing():
+		case <-data.Drivers():
+		case <-data.Event():
+		case <-timeout:
+			t.Fatal("timed out playing the session")
+		}
+	}
+	data.Close()
+
+	if _, err = parser.LoadKeyframes(keyframeFile, parser.Drivers); err == nil {
+		t.Error("keyframes for different data shouldn't load")
+	}
+
+	keyframes, err := parser.LoadKeyframes(keyframeFile, requested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyframes.Len() < 5 {
+		t.Errorf("only %d keyframes were saved", keyframes.Len())
+	}
+
+	target := time.Date(2023, 3, 5, 15, 0, 4, 500000000, time.UTC)
+	from, exists := keyframes.Before(target)
+	if !exists || from.After(target) || target.Sub(from) >= time.Second {
+		t.Errorf("nearest keyframe to %s is %s", target, from)
+	}
+
+	// A new session can carry on from the saved state without the data before it
+	resumed, err := f1gopherlib.CreateReplayFromDir(
+		requested,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough,
+		f1gopherlib.WithKeyframeFile(keyframeFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+
+	if err = resumed.SeekTo(target); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restored state is sent first so the lead change before the keyframe is already there
+	leader := 0
+	timeout = time.After(10 * time.Second)
+	for leader == 0 {
+		select {
+		case timing := <-resumed.Timing():
+			if timing.Position == 1 {
+				leader = timing.Number
+			}
+
+		case <-resumed.Drivers():
+		case <-resumed.Event():
+		case <-resumed.Time():
+		case <-timeout:
+			t.Fatal("timed out waiting for the restored timing")
+		}
+	}
+
+	if leader != 44 {
+		t.Errorf("expected the restored leader to be 44 but got %d", leader)
+	}
+}
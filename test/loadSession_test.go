@@ -0,0 +1,130 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+func TestLoadSession(t *testing.T) {
+	session, err := f1gopherlib.LoadSessionFromFS(os.DirFS(fixtureDir), fixtureEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(session.Drivers) == 0 || len(session.Weather) == 0 || len(session.Telemetry) == 0 {
+		t.Errorf("missing data: %d drivers, %d weather, %d telemetry",
+			len(session.Drivers), len(session.Weather), len(session.Telemetry))
+	}
+
+	if len(session.RaceControl) != 1 || session.RaceControl[0].Msg != "GREEN LIGHT - PIT EXIT OPEN" {
+		t.Errorf("unexpected race control messages: %v", session.RaceControl)
+	}
+
+	if len(session.Radio) != 1 {
+		t.Errorf("expected 1 team radio message but got %d", len(session.Radio))
+	}
+
+	if last := session.Events[len(session.Events)-1]; last.Status != Messages.Finished {
+		t.Errorf("the session ended with the status %s", last.Status)
+	}
+
+	if numbers := session.DriverNumbers(); !reflect.DeepEqual(numbers, []int{1, 44}) {
+		t.Errorf("unexpected drivers: %v", numbers)
+	}
+
+	for _, timing := range session.TimingFor(44) {
+		if timing.Number != 44 {
+			t.Errorf("got timing for %d in the timing for 44", timing.Number)
+		}
+	}
+
+	// 44 takes the lead 3.5 seconds in
+	for _, check := range []struct {
+		at       time.Time
+		position int
+	}{
+		{time.Date(2023, 3, 5, 15, 0, 2, 0, time.UTC), 2},
+		{time.Date(2023, 3, 5, 15, 0, 4, 0, time.UTC), 1},
+	} {
+		timing, exists := session.TimingAt(44, check.at)
+		if !exists || timing.Position != check.position {
+			t.Errorf("44 was P%d at %s, expected P%d", timing.Position, check.at, check.position)
+		}
+	}
+
+	if _, exists := session.EventAt(time.Date(2023, 3, 5, 14, 0, 0, 0, time.UTC)); exists {
+		t.Error("there shouldn't be an event before the session")
+	}
+
+	if _, err = f1gopherlib.LoadSessionFromFS(os.DirFS(t.TempDir()), fixtureEvent()); err == nil {
+		t.Error("loading a session without any data should fail")
+	}
+}
+
+func TestReadSessionMatchesBatchReplay(t *testing.T) {
+	log := f1log.CreateLog()
+	files := os.DirFS(fixtureDir)
+
+	session, err := connection.ReadSession(files, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	replay := connection.CreateFsReplay(ctx, wg, log, files, Messages.RaceSession, 2023)
+	replay.SetBatch(true)
+	err, feed := replay.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batch []connection.Payload
+	timeout := time.After(10 * time.Second)
+	for reading := true; reading; {
+		select {
+		case payload := <-feed:
+			switch payload.Name {
+			case connection.EndOfDataFile:
+				reading = false
+			case connection.KeyframeFile:
+			default:
+				batch = append(batch, payload)
+			}
+
+		case <-timeout:
+			t.Fatal("timed out waiting for the replay to finish")
+		}
+	}
+
+	if len(session) == 0 || !reflect.DeepEqual(session, batch) {
+		t.Errorf("ReadSession and the batch replay gave different data, %d and %d payloads", len(session), len(batch))
+	}
+}
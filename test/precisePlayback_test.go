@@ -0,0 +1,88 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+)
+
+func TestPrecisePlayback(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	telemetry := make(chan Messages.Telemetry, 100)
+	eventTime := make(chan Messages.EventTime, 100)
+
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              make(chan Messages.Timing, 10),
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           telemetry,
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			Granularity:         20 * time.Millisecond,
+		},
+		f1log.CreateLog())
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	for x := 1; x <= 10; x++ {
+		flow.AddTelemetry(Messages.Telemetry{Timestamp: start.Add(time.Duration(x) * 150 * time.Millisecond), DriverNumber: x})
+	}
+	go flow.Run()
+
+	// Each message arrives at its own time instead of all together
+	var arrived []time.Time
+	timeout := time.After(10 * time.Second)
+	for len(arrived) < 10 {
+		select {
+		case <-telemetry:
+			arrived = append(arrived, time.Now())
+		case <-eventTime:
+		case <-timeout:
+			t.Fatalf("timed out after %d telemetry messages", len(arrived))
+		}
+	}
+
+	if spread := arrived[9].Sub(arrived[0]); spread < time.Second {
+		t.Errorf("expected the telemetry to be spread over 1.35s, took %s", spread)
+	}
+
+	for x := 1; x < len(arrived); x++ {
+		if gap := arrived[x].Sub(arrived[x-1]); gap > 400*time.Millisecond {
+			t.Errorf("telemetry %d arrived %s after the one before", x+1, gap)
+		}
+	}
+}
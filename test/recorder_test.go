@@ -0,0 +1,165 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/fakeLive"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// The lines of a recorded '<Topic>.jsonStream' file
+func recordedLines(t *testing.T, dir string, name string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".jsonStream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRecording(t *testing.T) {
+	assets, _ := fixtureServer(t)
+
+	// Joined late so the catchup has the clock that started counting down a minute before the live data
+	server := fakeLive.Create([]connection.Payload{
+		{
+			Name: connection.CatchupFile,
+			Data: []byte(`{"DriverList":` + fixtureDriverList(t) + `,` +
+				`"SessionInfo":{"Meeting":{"Name":"Test Grand Prix"},"Name":"Race","Type":"Race"},` +
+				`"ExtrapolatedClock":{"Utc":"2023-03-05T14:59:00.000Z","Remaining":"01:00:00","Extrapolating":true}}`),
+		},
+		{Name: connection.HeartbeatFile, Data: []byte(`{"Utc":"2023-03-05T14:59:59.000Z"}`), Timestamp: "2023-03-05T14:59:59Z"},
+		{
+			Name:      connection.TimingDataFile,
+			Data:      []byte(`{"Lines":{"1":{"Position":"1"},"44":{"Position":"2"}}}`),
+			Timestamp: "2023-03-05T15:00:00Z",
+		},
+		{
+			Name:      connection.TimingDataFile,
+			Data:      []byte(`{"Lines":{"44":{"Position":"1"},"1":{"Position":"2"}}}`),
+			Timestamp: "2023-03-05T15:00:01.25Z",
+		},
+	}, 4, f1log.CreateLog())
+	live := httptest.NewServer(server)
+	t.Cleanup(live.Close)
+
+	event := fixtureEvent()
+	recording := t.TempDir()
+
+	data, err := f1gopherlib.CreateLiveRealtime(
+		parser.Drivers|parser.Timing|parser.Event,
+		f1gopherlib.WithLiveEvent(event),
+		f1gopherlib.WithLiveUrl(live.URL+"/signalr"),
+		f1gopherlib.WithBaseUrl(assets.URL+"/static/"),
+		f1gopherlib.WithRecording(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectStates(t, data.ConnectionStatus(), Messages.Connecting, Messages.Connected)
+
+	timeout := time.After(10 * time.Second)
+	for leader := 0; leader != 44; {
+		select {
+		case timing := <-data.Timing():
+			if timing.Position == 1 {
+				leader = timing.Number
+			}
+		case <-data.Drivers():
+		case <-data.Event():
+		case <-data.Time():
+		case <-timeout:
+			t.Fatal("timed out waiting for the change of lead")
+		}
+	}
+	data.Close()
+
+	recorded := filepath.Join(recording, "2023", "2023-03-05_Test Grand Prix", "Race")
+
+	// Offset from the first live data, with the catchup written at the start
+	timing := recordedLines(t, recorded, connection.TimingDataFile)
+	if len(timing) != 2 || !strings.HasPrefix(timing[0], "00:00:00.000{") || !strings.HasPrefix(timing[1], "00:00:01.250{") {
+		t.Errorf("unexpected recorded timing offsets: %v", timing)
+	}
+
+	for _, name := range []string{connection.DriverListFile, connection.SessionInfoFile, connection.HeartbeatFile} {
+		lines := recordedLines(t, recorded, name)
+		if !strings.HasPrefix(lines[0], "00:00:00.000{") {
+			t.Errorf("expected the %s catchup at the start but got %s", name, lines[0])
+		}
+	}
+
+	// The clock is moved on to when the catchup was written
+	clockLines := recordedLines(t, recorded, connection.ExtrapolatedClockFile)
+	if !strings.HasPrefix(clockLines[0], "00:00:00.000{") {
+		t.Fatalf("expected the clock catchup at the start but got %s", clockLines[0])
+	}
+	var clock struct {
+		Utc           string
+		Remaining     string
+		Extrapolating bool
+	}
+	err = json.Unmarshal([]byte(clockLines[0][len("00:00:00.000"):]), &clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clock.Utc != "2023-03-05T15:00:00.000Z" || clock.Remaining != "00:59:00" || !clock.Extrapolating {
+		t.Errorf("got recorded clock %+v, expected 59 minutes remaining at the start of the data", clock)
+	}
+
+	// Replayed at the times it was received
+	replay, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing,
+		recorded,
+		event,
+		flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	changeOfLead := time.Date(2023, 3, 5, 15, 0, 1, 250000000, time.UTC)
+	timeout = time.After(10 * time.Second)
+	for {
+		select {
+		case timing := <-replay.Timing():
+			if timing.Number == 44 && timing.Position == 1 {
+				if !timing.Timestamp.Equal(changeOfLead) {
+					t.Errorf("got the change of lead at %s, expected %s", timing.Timestamp, changeOfLead)
+				}
+				return
+			}
+		case <-replay.Drivers():
+		case <-timeout:
+			t.Fatal("timed out waiting for the recorded change of lead")
+		}
+	}
+}
@@ -41,7 +41,7 @@ func TestReplay(t *testing.T) {
 
 		t.Logf("Testing: %d %d - %s %s...", x, session.RaceTime.Year(), session.Country, session.Type.String())
 
-		replay := connection.CreateReplay(nil, nil, log, session.Url(), session.Type, session.RaceTime.Year(), "")
+		replay := connection.CreateReplay(nil, nil, log, session.Url(), session.Type, session.RaceTime.Year(), "", nil)
 		err, payload := replay.Connect()
 
 		if err != nil {
@@ -52,7 +52,8 @@ func TestReplay(t *testing.T) {
 		assetStore := connection.CreateAssetStore(
 			session.Url(),
 			filepath.Join("./cache", strings.Replace(session.Url(), "https://livetiming.formula1.com/static/", "", 1)),
-			log)
+			log,
+			nil)
 
 		p := parser.Create(nil, nil, parser.EventTime|parser.Event|parser.RaceControl|parser.Weather|parser.Timing|parser.Telemetry|parser.Location|parser.TeamRadio,
 			payload,
@@ -83,6 +84,12 @@ func (d *dummyFlowControl) IncrementTime(duration time.Duration)
 func (d *dummyFlowControl) SkipToSessionStart(start time.Time)                            {}
 func (d *dummyFlowControl) TogglePause()                                                  {}
 func (d *dummyFlowControl) IsPaused() bool                                                { return false }
+func (d *dummyFlowControl) SetSpeed(speed float64)                                        {}
+func (d *dummyFlowControl) SeekTo(target time.Time)                                       {}
+func (d *dummyFlowControl) NextSeek() <-chan struct{}                                     { return nil }
+func (d *dummyFlowControl) SendToSubscriptions(output chan Messages.Envelope)             {}
+func (d *dummyFlowControl) Snapshot() Messages.Snapshot                                   { return Messages.Snapshot{} }
+func (d *dummyFlowControl) Dropped() map[Messages.StreamType]uint64                       { return nil }
 func (d *dummyFlowControl) IncrementDelay(delay time.Duration)                            {}
 func (d *dummyFlowControl) DecrementDelay(delay time.Duration)                            {}
 func (d *dummyFlowControl) Delay() time.Duration                                          { return 0 }
@@ -0,0 +1,269 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// Reads everything until the time reaches target, returning the last leader seen
+func waitForTime(t *testing.T, data f1gopherlib.F1GopherLib, target time.Time) int {
+	t.Helper()
+
+	leader := 0
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case eventTime := <-data.Time():
+			if eventTime.Timestamp.Equal(target) {
+				return leader
+			}
+
+		case timing := <-data.Timing():
+			if timing.Position == 1 {
+				leader = timing.Number
+			}
+
+		case <-data.Drivers():
+		case <-data.Event():
+		case <-timeout:
+			t.Fatalf("timed out waiting for the time to reach %s", target)
+		}
+	}
+}
+
+// Reads everything until the given driver is reported as leading
+func waitForLeader(t *testing.T, data f1gopherlib.F1GopherLib, number int) Messages.Timing {
+	t.Helper()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case timing := <-data.Timing():
+			if timing.Position == 1 && timing.Number == number {
+				return timing
+			}
+
+		case <-data.Time():
+		case <-data.Drivers():
+		case <-data.Event():
+		case <-timeout:
+			t.Fatalf("timed out waiting for %d to lead", number)
+		}
+	}
+}
+
+func TestReplaySeekBackwards(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	if err = data.SeekToLap(10); err == nil {
+		t.Error("seeking to a lap that doesn't exist should fail")
+	}
+
+	// 44 takes the lead 3.5 seconds in
+	waitForLeader(t, data, 44)
+
+	// Going back to the start of lap 2 rebuilds the timing from the beginning so 1 is leading again
+	if err = data.SeekToLap(2); err != nil {
+		t.Fatal(err)
+	}
+	timing := waitForLeader(t, data, 1)
+	if !timing.Timestamp.Before(time.Date(2023, 3, 5, 15, 0, 3, 0, time.UTC)) {
+		t.Errorf("expected the timing from before lap 2 but it was from %s", timing.Timestamp)
+	}
+}
+
+func TestReplaySeekToRemaining(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	if err = data.SeekToRemaining(2 * time.Hour); err == nil {
+		t.Error("seeking to a time remaining that never happened should fail")
+	}
+
+	// The clock starts running 2 seconds in so 2 seconds have gone 4 seconds in
+	if err = data.SeekToRemaining(time.Hour - 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// Only the latest timing from before the seek is sent so 1 never leads
+	if leader := waitForTime(t, data, time.Date(2023, 3, 5, 15, 0, 4, 0, time.UTC)); leader != 44 {
+		if leader == 1 {
+			t.Error("the timing from before 44 took the lead was sent")
+		}
+		waitForLeader(t, data, 44)
+	}
+}
+
+// Run with -race to check seeking is applied by the time it returns, even while paused
+func TestReplaySeekWhilePaused(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	waitForTime(t, data, time.Date(2023, 3, 5, 15, 0, 2, 200000000, time.UTC))
+	data.TogglePause()
+
+	for _, target := range []time.Time{
+		time.Date(2023, 3, 5, 15, 0, 4, 0, time.UTC),
+		time.Date(2023, 3, 5, 15, 0, 1, 0, time.UTC),
+	} {
+		if err = data.SeekTo(target); err != nil {
+			t.Fatal(err)
+		}
+		if current := data.Snapshot().Timestamp; !current.Equal(target) {
+			t.Errorf("got the time %s straight after seeking, expected %s", current, target)
+		}
+	}
+
+	if !data.IsPaused() {
+		t.Error("seeking shouldn't carry on playing")
+	}
+
+	// Carries on from the seek when playing again
+	data.TogglePause()
+	waitForTime(t, data, time.Date(2023, 3, 5, 15, 0, 1, 500000000, time.UTC))
+}
+
+func TestReplaySeekIndexIsOnlyRead(t *testing.T) {
+	server, requests := fixtureServer(t)
+	event := fixtureEvent()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replay := connection.CreateReplay(ctx, &sync.WaitGroup{}, f1log.CreateLog(), event.UrlFrom(server.URL+"/static/"),
+		event.Type, event.RaceTime.Year(), "", nil)
+
+	for x := 0; x < 3; x++ {
+		if _, err := replay.LapStartTime(2); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := replay.TimeWhenRemaining(time.Hour - 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		// The session start time, the lap count and the session clock
+		if made := atomic.LoadInt32(requests); made != 3 {
+			t.Fatalf("expected the files to only be downloaded once but %d requests were made", made)
+		}
+	}
+}
+
+// Counts the files opened and closed
+type closeCountingFS struct {
+	fs.FS
+	opened int32
+	closed int32
+}
+
+type closeCountingFile struct {
+	fs.File
+	files *closeCountingFS
+}
+
+func (c *closeCountingFS) Open(name string) (fs.File, error) {
+	f, err := c.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt32(&c.opened, 1)
+	return &closeCountingFile{File: f, files: c}, nil
+}
+
+func (c *closeCountingFile) Close() error {
+	atomic.AddInt32(&c.files.closed, 1)
+	return c.File.Close()
+}
+
+func TestReplaySeekClosesFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	files := &closeCountingFS{FS: os.DirFS(fixtureDir)}
+
+	replay := connection.CreateFsReplay(ctx, wg, f1log.CreateLog(), files, Messages.RaceSession, 2023)
+	replay.SetSpeed(10)
+	err, feed := replay.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeked := false
+	timeout := time.After(10 * time.Second)
+	for restarted := false; !restarted; {
+		select {
+		case payload := <-feed:
+			switch payload.Name {
+			case connection.KeyframeFile:
+				// Go back to the start once some data has been sent so the files are read again
+				if !seeked && payload.Timestamp >= "2023-03-05T15:00:02Z" {
+					if err = replay.SeekTo(time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC), time.Time{}); err != nil {
+						t.Fatal(err)
+					}
+					seeked = true
+				}
+
+			case connection.SeekFile:
+				restarted = string(payload.Data) == connection.SeekRestart
+			}
+
+		case <-timeout:
+			t.Fatal("timed out waiting for the replay to restart")
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	opened := atomic.LoadInt32(&files.opened)
+	if closed := atomic.LoadInt32(&files.closed); closed != opened {
+		t.Errorf("%d files were opened but only %d were closed", opened, closed)
+	}
+}
@@ -0,0 +1,225 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+)
+
+const recordSeparator = "\x1e"
+
+// A stand-in for the SignalR Core hub. The first connection is closed by the server after sending some data so
+// the client has to reconnect.
+func coreHub(t *testing.T, useTls bool) (*httptest.Server, *int32) {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/signalrcore/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("negotiateVersion") != "1" {
+			http.Error(w, "bad negotiate", http.StatusBadRequest)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "affinity", Value: "server1"})
+		w.Write([]byte(`{"negotiateVersion":1,"connectionId":"id","connectionToken":"token",` +
+			`"availableTransports":[{"transport":"WebSockets","transferFormats":["Text","Binary"]}]}`))
+	})
+	mux.HandleFunc("/signalrcore", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") != "token" {
+			http.Error(w, "unknown connection", http.StatusNotFound)
+			return
+		}
+		if cookie, err := r.Cookie("affinity"); err != nil || cookie.Value != "server1" {
+			http.Error(w, "missing affinity cookie", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		count := atomic.AddInt32(&connections, 1)
+
+		_, handshake, err := conn.ReadMessage()
+		if err != nil || string(handshake) != `{"protocol":"json","version":1}`+recordSeparator {
+			t.Errorf("unexpected handshake '%s': %v", handshake, err)
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte("{}"+recordSeparator))
+
+		_, invocation, err := conn.ReadMessage()
+		if err != nil {
+			t.Errorf("reading subscribe: %v", err)
+			return
+		}
+		var subscribe struct {
+			Type         int        `json:"type"`
+			InvocationId string     `json:"invocationId"`
+			Target       string     `json:"target"`
+			Arguments    [][]string `json:"arguments"`
+		}
+		err = json.Unmarshal(bytes.TrimSuffix(invocation, []byte(recordSeparator)), &subscribe)
+		if err != nil || subscribe.Type != 1 || subscribe.Target != "Subscribe" || len(subscribe.Arguments) != 1 {
+			t.Errorf("unexpected subscribe '%s': %v", invocation, err)
+			return
+		}
+		if len(subscribe.Arguments[0]) != len(connection.OrderedFiles) {
+			t.Errorf("subscribed to %d topics, expected %d", len(subscribe.Arguments[0]), len(connection.OrderedFiles))
+		}
+
+		// Several messages in one frame
+		conn.WriteMessage(websocket.TextMessage, []byte(
+			`{"type":3,"invocationId":"`+subscribe.InvocationId+`","result":{"Heartbeat":{"Utc":"2023-03-05T15:00:00.000Z"}}}`+recordSeparator+
+				`{"type":6}`+recordSeparator+
+				`{"type":1,"target":"feed","arguments":["Heartbeat",{"Utc":"2023-03-05T15:00:01.000Z"},"2023-03-05T15:00:01.000Z"]}`+recordSeparator))
+		conn.WriteMessage(websocket.TextMessage, []byte(
+			`{"type":1,"target":"feed","arguments":["CarData.z","7ZQxDsIwDEX3","2023-03-05T15:00:02.000Z"]}`+recordSeparator))
+
+		if count == 1 {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"type":7,"error":"server restarting"}`+recordSeparator))
+			return
+		}
+
+		// Stay connected until the client goes away
+		for {
+			if _, _, err = conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	server := httptest.NewUnstartedServer(mux)
+	if useTls {
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	t.Cleanup(server.Close)
+
+	return server, &connections
+}
+
+func TestLiveSignalRCore(t *testing.T) {
+	server, connections := coreHub(t, false)
+
+	log := f1log.CreateLog()
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	status := make(chan Messages.ConnectionStatus, 10)
+
+	live := connection.CreateLiveCore(ctx, &wg, log, server.URL+"/signalrcore", server.Client())
+	live.SetStatusOutput(status)
+
+	err, data := live.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []connection.Payload{
+		{Name: connection.CatchupFile, Data: []byte(`{"Heartbeat":{"Utc":"2023-03-05T15:00:00.000Z"}}`)},
+		{Name: connection.HeartbeatFile, Data: []byte(`{"Utc":"2023-03-05T15:00:01.000Z"}`), Timestamp: "2023-03-05T15:00:01.000Z"},
+		{Name: connection.CarDataFile, Data: []byte("7ZQxDsIwDEX3"), Timestamp: "2023-03-05T15:00:02.000Z"},
+		// After reconnecting the catchup is timestamped with the last data received
+		{Name: connection.CatchupFile, Data: []byte(`{"Heartbeat":{"Utc":"2023-03-05T15:00:00.000Z"}}`), Timestamp: "2023-03-05T15:00:02.000Z"},
+		{Name: connection.HeartbeatFile, Data: []byte(`{"Utc":"2023-03-05T15:00:01.000Z"}`), Timestamp: "2023-03-05T15:00:01.000Z"},
+	}
+
+	for _, want := range expected {
+		select {
+		case got := <-data:
+			if got.Name != want.Name || !bytes.Equal(got.Data, want.Data) || got.Timestamp != want.Timestamp {
+				t.Errorf("got payload %s '%s' at '%s', expected %s '%s' at '%s'",
+					got.Name, got.Data, got.Timestamp, want.Name, want.Data, want.Timestamp)
+			}
+
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for %s", want.Name)
+		}
+	}
+
+	states := []Messages.ConnectionState{
+		Messages.Connecting,
+		Messages.Connected,
+		Messages.Reconnecting,
+		Messages.Connected,
+	}
+	for _, want := range states {
+		select {
+		case got := <-status:
+			if got.State != want {
+				t.Errorf("got connection state %s, expected %s", got.State, want)
+			}
+			if got.State == Messages.Reconnecting && !strings.Contains(got.Error, "server restarting") {
+				t.Errorf("reconnect reason is '%s'", got.Error)
+			}
+
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for connection state %s", want)
+		}
+	}
+
+	if count := atomic.LoadInt32(connections); count != 2 {
+		t.Errorf("connected %d times, expected 2", count)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+// The websocket has to trust the same certificates as the client used for the negotiate request
+func TestLiveSignalRCoreClientTls(t *testing.T) {
+	server, _ := coreHub(t, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	live := connection.CreateLiveCore(ctx, &wg, f1log.CreateLog(), server.URL+"/signalrcore", server.Client())
+	err, data := live.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-data:
+		if got.Name != connection.CatchupFile {
+			t.Errorf("got payload %s, expected the catchup", got.Name)
+		}
+
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the catchup")
+	}
+}
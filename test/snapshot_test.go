@@ -0,0 +1,66 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func TestSnapshot(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime|parser.RaceControl,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	leader := waitForLeader(t, data, 44)
+
+	snapshot := data.Snapshot()
+	if snapshot.Timing[44].Position != 1 || !snapshot.Timing[44].Timestamp.Equal(leader.Timestamp) {
+		t.Errorf("expected 44 to be leading, got %+v", snapshot.Timing[44])
+	}
+
+	if len(snapshot.Drivers.Drivers) == 0 {
+		t.Error("no drivers in the snapshot")
+	}
+
+	if snapshot.Event.Timestamp.IsZero() {
+		t.Error("no event in the snapshot")
+	}
+
+	if len(snapshot.RaceControl) != 1 {
+		t.Errorf("expected the race control message before the lead changed, got %d", len(snapshot.RaceControl))
+	}
+
+	// Changing a snapshot doesn't change the next one
+	snapshot.Drivers.Drivers[0].Number = -1
+	delete(snapshot.Timing, 44)
+	snapshot.RaceControl[0].Msg = "changed"
+
+	again := data.Snapshot()
+	if again.Drivers.Drivers[0].Number == -1 || again.Timing[44].Number != 44 || again.RaceControl[0].Msg == "changed" {
+		t.Error("changing a snapshot changed the session state")
+	}
+}
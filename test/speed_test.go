@@ -0,0 +1,85 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func TestReplaySpeed(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.EventTime|parser.Event|parser.RaceControl,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	data.SetSpeed(0)
+	if data.Speed() != 1 {
+		t.Errorf("invalid speed was used: %v", data.Speed())
+	}
+
+	data.SetSpeed(10)
+	if data.Speed() != 10 {
+		t.Errorf("speed is %v, expected 10", data.Speed())
+	}
+
+	start := time.Now()
+	var times []Messages.EventTime
+	timeout := time.After(10 * time.Second)
+
+	// The race control message is 2.5 seconds into the data
+	for received := false; !received; {
+		select {
+		case <-data.RaceControlMessages():
+			received = true
+		case eventTime := <-data.Time():
+			times = append(times, eventTime)
+		case <-data.Event():
+		case <-timeout:
+			t.Fatal("timed out waiting for the race control message")
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %s to play 2.5 seconds of data at 10x", elapsed)
+	}
+
+	if len(times) < 2 {
+		t.Fatalf("only got %d time updates", len(times))
+	}
+
+	// The time moves on the same amount whatever the speed so everything stays in sync
+	for x := 1; x < len(times); x++ {
+		if step := times[x].Timestamp.Sub(times[x-1].Timestamp); step != 500*time.Millisecond {
+			t.Errorf("time moved on by %s between updates, expected 500ms", step)
+		}
+	}
+
+	data.SetSpeed(0.5)
+	if data.Speed() != 0.5 {
+		t.Errorf("speed is %v, expected 0.5", data.Speed())
+	}
+}
@@ -0,0 +1,192 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	telemetry := make(chan Messages.Telemetry, 1000)
+	eventTime := make(chan Messages.EventTime, 1000)
+
+	// Only enough memory for a couple of messages
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              make(chan Messages.Timing, 10),
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           telemetry,
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           eventTime,
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			MemoryLimit:         200,
+			SpillDir:            dir,
+		},
+		f1log.CreateLog())
+
+	flow.SetSpeed(10)
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	for x := 0; x < 100; x++ {
+		flow.AddTelemetry(Messages.Telemetry{Timestamp: start.Add(time.Duration(x) * 10 * time.Millisecond), DriverNumber: x})
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected the telemetry to be written to disk, found %d files", len(files))
+	}
+
+	go flow.Run()
+
+	timeout := time.After(5 * time.Second)
+	for x := 0; x < 100; x++ {
+		select {
+		case msg := <-telemetry:
+			if msg.DriverNumber != x {
+				t.Fatalf("expected telemetry %d, got %d", x, msg.DriverNumber)
+			}
+		case <-eventTime:
+			x--
+		case <-timeout:
+			t.Fatalf("timed out after %d telemetry messages", x)
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	files, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected the spill file to be removed, found %d files", len(files))
+	}
+}
+
+func TestMemoryLimitTimingAndStream(t *testing.T) {
+	start := time.Date(2023, 3, 5, 15, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+
+	timing := make(chan Messages.Timing, 1000)
+	stream := make(chan Messages.Envelope, 1000)
+
+	// Only enough memory for a few messages
+	flow := flowControl.CreateFlowControl(
+		ctx,
+		&wg,
+		flowControl.Realtime,
+		flowControl.Config{
+			Weather:             make(chan Messages.Weather, 10),
+			RaceControlMessages: make(chan Messages.RaceControlMessage, 10),
+			Timing:              timing,
+			Event:               make(chan Messages.Event, 10),
+			Telemetry:           make(chan Messages.Telemetry, 10),
+			Location:            make(chan Messages.Location, 10),
+			EventTime:           make(chan Messages.EventTime, 1000),
+			Radio:               make(chan Messages.Radio, 10),
+			Drivers:             make(chan Messages.Drivers, 10),
+			Stream:              stream,
+			MemoryLimit:         4000,
+			SpillDir:            dir,
+		},
+		f1log.CreateLog())
+
+	flow.SetSpeed(10)
+	flow.AddEvent(Messages.Event{Timestamp: start})
+	for x := 0; x < 100; x++ {
+		flow.AddTiming(Messages.Timing{
+			Timestamp:    start.Add(time.Duration(x) * 10 * time.Millisecond),
+			Number:       x,
+			Name:         "Driver",
+			PitStopTimes: []Messages.PitStop{{Lap: x}},
+		})
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the timing and stream to be written to disk, found %d files", len(files))
+	}
+
+	go flow.Run()
+
+	timeout := time.After(5 * time.Second)
+	for x := 0; x < 100; x++ {
+		select {
+		case msg := <-timing:
+			if msg.Number != x || msg.Name != "Driver" || len(msg.PitStopTimes) != 1 || msg.PitStopTimes[0].Lap != x {
+				t.Fatalf("expected timing %d, got %+v", x, msg)
+			}
+		case <-timeout:
+			t.Fatalf("timed out after %d timing messages", x)
+		}
+	}
+
+	for x := 0; x < 100; {
+		select {
+		case envelope := <-stream:
+			if envelope.Type != Messages.TimingStream {
+				continue
+			}
+			msg, ok := envelope.Data.(Messages.Timing)
+			if !ok || msg.Number != x {
+				t.Fatalf("expected timing %d in the stream, got %+v", x, envelope.Data)
+			}
+			x++
+		case <-timeout:
+			t.Fatalf("timed out after %d timing messages in the stream", x)
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	files, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected the spill files to be removed, found %d files", len(files))
+	}
+}
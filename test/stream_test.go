@@ -0,0 +1,152 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+// Reads the stream and the typed channels until the session has finished, returning the stream and how many of each
+// type were sent to the typed channels
+func readStream(t *testing.T, data f1gopherlib.F1GopherLib) ([]Messages.Envelope, map[Messages.StreamType]int) {
+	t.Helper()
+
+	var result []Messages.Envelope
+	typed := map[Messages.StreamType]int{}
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case envelope := <-data.Stream():
+			result = append(result, envelope)
+			if envelope.Type == Messages.EventStream && envelope.Data.(Messages.Event).Status == Messages.Finished {
+				typed[Messages.TimingStream] += len(data.Timing())
+				typed[Messages.EventStream] += len(data.Event())
+				return result, typed
+			}
+
+		case <-data.Timing():
+			typed[Messages.TimingStream]++
+		case <-data.Event():
+			typed[Messages.EventStream]++
+		case <-data.Time():
+			typed[Messages.EventTimeStream]++
+		case <-data.Drivers():
+			typed[Messages.DriversStream]++
+		case <-data.RaceControlMessages():
+			typed[Messages.RaceControlStream]++
+
+		case <-timeout:
+			t.Fatal("timed out reading the stream")
+		}
+	}
+}
+
+func checkStream(t *testing.T, stream []Messages.Envelope) {
+	t.Helper()
+
+	types := map[Messages.StreamType]bool{}
+	for x := range stream {
+		if stream[x].Sequence != uint64(x+1) {
+			t.Fatalf("message %d has sequence %d", x, stream[x].Sequence)
+		}
+		types[stream[x].Type] = true
+	}
+
+	for _, streamType := range []Messages.StreamType{
+		Messages.DriversStream,
+		Messages.TimingStream,
+		Messages.EventStream,
+		Messages.EventTimeStream,
+		Messages.RaceControlStream} {
+
+		if !types[streamType] {
+			t.Errorf("no %s messages were sent", streamType)
+		}
+	}
+
+	// The race control message at 2.5s comes before 44 takes the lead at 3.5s
+	raceControl := -1
+	newLeader := -1
+	for x := range stream {
+		switch stream[x].Type {
+		case Messages.RaceControlStream:
+			raceControl = x
+		case Messages.TimingStream:
+			timing := stream[x].Data.(Messages.Timing)
+			if newLeader == -1 && timing.Number == 44 && timing.Position == 1 {
+				newLeader = x
+			}
+		}
+	}
+	if raceControl == -1 || newLeader == -1 || newLeader < raceControl {
+		t.Errorf("race control at %d should be before the new leader at %d", raceControl, newLeader)
+	}
+}
+
+func TestStreamBatch(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime|parser.RaceControl,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough,
+		f1gopherlib.WithBatch(),
+		f1gopherlib.WithStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	stream, typed := readStream(t, data)
+	checkStream(t, stream)
+
+	// Everything sent to the stream is also sent to its own channel
+	timing := 0
+	for x := range stream {
+		if stream[x].Type == Messages.TimingStream {
+			timing++
+		}
+	}
+	if typed[Messages.TimingStream] != timing {
+		t.Errorf("%d timing messages were sent to the stream but %d to the timing channel", timing, typed[Messages.TimingStream])
+	}
+}
+
+func TestStreamRealtime(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime|parser.RaceControl,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime,
+		f1gopherlib.WithStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	stream, typed := readStream(t, data)
+	checkStream(t, stream)
+
+	if typed[Messages.TimingStream] == 0 || typed[Messages.EventStream] == 0 {
+		t.Error("data wasn't also sent to the typed channels")
+	}
+}
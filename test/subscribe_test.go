@@ -0,0 +1,180 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func TestSubscribe(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	var lock sync.Mutex
+	var timing []Messages.Timing
+	var driverLists int
+	finished := make(chan struct{})
+
+	_, err = data.Subscribe(Messages.TimingStream, []int{44}, func(envelope Messages.Envelope) error {
+		lock.Lock()
+		defer lock.Unlock()
+		timing = append(timing, envelope.Data.(Messages.Timing))
+		return errors.New("a failing handler doesn't stop the others")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = data.Subscribe(Messages.EventStream, nil, func(envelope Messages.Envelope) error {
+		if envelope.Data.(Messages.Event).Status == Messages.Finished {
+			close(finished)
+		}
+		panic("a panicking handler doesn't stop the others")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsubscribe, err := data.Subscribe(Messages.DriversStream, nil, func(envelope Messages.Envelope) error {
+		lock.Lock()
+		defer lock.Unlock()
+		driverLists++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+
+	// The time waits to be read
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case <-finished:
+			done = true
+		case <-data.Time():
+		case <-timeout:
+			t.Fatal("timed out waiting for the session to finish")
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(timing) == 0 {
+		t.Error("no timing was sent")
+	}
+	for _, msg := range timing {
+		if msg.Number != 44 {
+			t.Errorf("timing for %d was sent to a subscription for 44", msg.Number)
+		}
+	}
+
+	if driverLists != 0 {
+		t.Errorf("%d driver lists were sent after unsubscribing", driverLists)
+	}
+}
+
+func TestSubscribeKeepsStream(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime|parser.RaceControl,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.Realtime,
+		f1gopherlib.WithStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	data.SetSpeed(10)
+
+	finished := make(chan struct{})
+	_, err = data.Subscribe(Messages.EventStream, nil, func(envelope Messages.Envelope) error {
+		if envelope.Data.(Messages.Event).Status == Messages.Finished {
+			close(finished)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stream still gets everything
+	stream, _ := readStream(t, data)
+	checkStream(t, stream)
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription didn't see the session finish")
+	}
+}
+
+func TestSubscribeSlowHandler(t *testing.T) {
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough,
+		f1gopherlib.WithBatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	// Never returns until the test has finished
+	release := make(chan struct{})
+	defer close(release)
+	_, err = data.Subscribe(Messages.TimingStream, nil, func(envelope Messages.Envelope) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-data.Event():
+			if event.Status == Messages.Finished {
+				return
+			}
+
+		case <-data.Timing():
+		case <-data.Time():
+		case <-data.Drivers():
+		case <-timeout:
+			t.Fatal("a slow handler held up the session")
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func TestTimingDelta(t *testing.T) {
+	previous := Messages.Timing{
+		Timestamp:   time.Date(2023, 3, 5, 15, 0, 1, 0, time.UTC),
+		Number:      44,
+		Position:    2,
+		GapToLeader: time.Second,
+		Tire:        Messages.Medium,
+	}
+
+	current := previous
+	current.Timestamp = previous.Timestamp.Add(time.Second)
+	current.Position = 1
+	current.GapToLeader = 0
+	current.PitStopTimes = []Messages.PitStop{{Lap: 3, PitlaneTime: 20 * time.Second}}
+
+	delta, err := Messages.CreateTimingDelta(previous, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(delta.Changes) != 3 {
+		t.Errorf("expected position, gap and pit stops to change, got %v", delta.Changes)
+	}
+
+	// Deltas are sent over the network so check they still apply after being encoded
+	data, err := json.Marshal(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Messages.TimingDelta
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	result := previous
+	if err = decoded.Apply(&result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, current) {
+		t.Errorf("expected %+v, got %+v", current, result)
+	}
+}
+
+func TestTimingDeltas(t *testing.T) {
+	t.Run("Channels", func(t *testing.T) {
+		checkTimingDeltas(t)
+	})
+
+	// The deltas are still sent when everything is also sent to the stream
+	t.Run("Stream", func(t *testing.T) {
+		checkTimingDeltas(t, f1gopherlib.WithStream())
+	})
+}
+
+// Rebuilds the timing for each driver from the deltas and checks it matches the latest timing
+func checkTimingDeltas(t *testing.T, options ...f1gopherlib.Option) {
+	t.Helper()
+
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Drivers|parser.Timing|parser.Event|parser.EventTime,
+		fixtureDir,
+		fixtureEvent(),
+		flowControl.StraightThrough,
+		append(options, f1gopherlib.WithBatch(), f1gopherlib.WithTimingDeltas())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	latest := map[int]Messages.Timing{}
+	rebuilt := map[int]Messages.Timing{}
+	timeout := time.After(5 * time.Second)
+
+	var idle <-chan time.Time
+	for reading := true; reading; {
+		select {
+		case msg := <-data.Timing():
+			latest[msg.Number] = msg
+
+		case delta := <-data.TimingDeltas():
+			timing := rebuilt[delta.Number]
+			if err = delta.Apply(&timing); err != nil {
+				t.Fatal(err)
+			}
+			rebuilt[delta.Number] = timing
+
+		case event := <-data.Event():
+			if event.Status == Messages.Finished {
+				idle = time.After(100 * time.Millisecond)
+			}
+
+		case <-data.Drivers():
+		case <-data.Time():
+		case <-data.Stream():
+		case <-idle:
+			reading = false
+		case <-timeout:
+			t.Fatal("timed out reading the session")
+		}
+	}
+
+	if len(latest) == 0 {
+		t.Fatal("no timing was sent")
+	}
+
+	for number, timing := range latest {
+		// Encoding the pit stops as json doesn't keep the difference between nil and empty
+		if len(timing.PitStopTimes) == 0 {
+			timing.PitStopTimes = rebuilt[number].PitStopTimes
+		}
+
+		if !reflect.DeepEqual(rebuilt[number], timing) {
+			t.Errorf("rebuilt timing for %d doesn't match:\nexpected %+v\ngot      %+v", number, timing, rebuilt[number])
+		}
+	}
+}
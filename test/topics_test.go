@@ -0,0 +1,146 @@
+// F1GopherLib - Copyright (C) 2022 f1gopher
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stephenhoran/f1gopherlib"
+	"github.com/stephenhoran/f1gopherlib/Messages"
+	"github.com/stephenhoran/f1gopherlib/connection"
+	"github.com/stephenhoran/f1gopherlib/f1log"
+	"github.com/stephenhoran/f1gopherlib/flowControl"
+	"github.com/stephenhoran/f1gopherlib/parser"
+)
+
+func TestTopics(t *testing.T) {
+	expected := []string{
+		connection.DriverListFile,
+		connection.SessionInfoFile,
+		connection.TimingDataFile,
+		connection.TimingAppDataFile,
+		connection.SessionStatusFile,
+		connection.SessionDataFile,
+		connection.CarDataFile,
+		connection.RaceControlMessagesFile,
+	}
+	if topics := parser.Topics(parser.Timing); !slices.Equal(topics, expected) {
+		t.Errorf("got timing topics %v, expected %v", topics, expected)
+	}
+
+	expected = []string{connection.DriverListFile, connection.SessionInfoFile, connection.PositionFile}
+	if topics := parser.Topics(parser.Location); !slices.Equal(topics, expected) {
+		t.Errorf("got location topics %v, expected %v", topics, expected)
+	}
+}
+
+func TestReplayTopics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	replay := connection.CreateFsReplay(ctx, wg, f1log.CreateLog(), os.DirFS(fixtureDir), Messages.RaceSession, 2023)
+	replay.SetTopics([]string{connection.TimingDataFile})
+	replay.SetBatch(true)
+
+	err, feed := replay.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timing := 0
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case payload := <-feed:
+			switch payload.Name {
+			case connection.EndOfDataFile:
+				if timing == 0 {
+					t.Error("expected the timing data to be sent")
+				}
+				return
+
+			case connection.KeyframeFile:
+
+			case connection.TimingDataFile:
+				timing++
+
+			default:
+				t.Errorf("got %s data which wasn't asked for", payload.Name)
+			}
+
+		case <-timeout:
+			t.Fatal("timed out waiting for the replay to finish")
+		}
+	}
+}
+
+func TestTimingOnlyPitStops(t *testing.T) {
+	// The fixture with 1 going into the pitlane after the race has started
+	dir := t.TempDir()
+	files, err := os.ReadDir(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(fixtureDir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Name() == connection.TimingDataFile+".jsonStream" {
+			data = append(data, []byte("00:00:04.000{\"Lines\":{\"1\":{\"Sectors\":{\"0\":{\"Segments\":{\"0\":{\"Status\":2064}}}}}}}\n")...)
+		}
+		err = os.WriteFile(filepath.Join(dir, file.Name()), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := f1gopherlib.CreateReplayFromDir(
+		parser.Timing,
+		dir,
+		fixtureEvent(),
+		flowControl.StraightThrough,
+		f1gopherlib.WithBatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case msg := <-data.Timing():
+			if msg.Number == 1 && len(msg.PitStopTimes) > 0 {
+				return
+			}
+
+		case <-timeout:
+			t.Fatal("timed out waiting for the pit stop")
+		}
+	}
+}